@@ -0,0 +1,314 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envsec
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotCached is returned by an offline CachedStore for environments that
+// have never been cached.
+var ErrNotCached = errors.New("no variables cached for offline use")
+
+// CacheOptions configures a CachedStore.
+type CacheOptions struct {
+	// Directory for the encrypted cache files. Defaults to envsec's directory
+	// in os.UserCacheDir.
+	Dir string
+	// File holding the machine-local encryption key. It is created on first
+	// use. Defaults to envsec's directory in os.UserConfigDir, so the key and
+	// the data it protects are kept apart.
+	KeyFile string
+	// How long a cached List result is used before asking the wrapped store
+	// again. Zero means always ask, but still refresh the cache.
+	TTL time.Duration
+	// Only serve reads from the cache and never call the wrapped store, which
+	// may then be nil.
+	Offline bool
+}
+
+func (o CacheOptions) withDefaults() (CacheOptions, error) {
+	if o.Dir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return o, errors.WithStack(err)
+		}
+		o.Dir = filepath.Join(dir, "envsec", "vars")
+	}
+	if o.KeyFile == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return o, errors.WithStack(err)
+		}
+		o.KeyFile = filepath.Join(dir, "envsec", "cache.key")
+	}
+	return o, nil
+}
+
+// CachedStore wraps a Store and keeps the results of List in encrypted files
+// on local disk, keyed by EnvID. Writes go to the wrapped store and drop the
+// cached copy of the environment they change.
+type CachedStore struct {
+	store Store
+	opts  CacheOptions
+	aead  cipher.AEAD
+	// logf reports problems that don't fail the call; replaced in tests.
+	logf func(format string, args ...any)
+}
+
+// CachedStore implements interface Store (compile-time check)
+var _ Store = (*CachedStore)(nil)
+
+// CachedStore implements interface EnvNameLister (compile-time check)
+var _ EnvNameLister = (*CachedStore)(nil)
+
+// CachedStore implements interface NameLister (compile-time check)
+var _ NameLister = (*CachedStore)(nil)
+
+// CachedStore implements interface Versioned (compile-time check)
+var _ Versioned = (*CachedStore)(nil)
+
+func NewCachedStore(store Store, opts CacheOptions) (*CachedStore, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	key, err := loadOrCreateCacheKey(opts.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &CachedStore{store: store, opts: opts, aead: aead, logf: logToStderr}, nil
+}
+
+// ClearCache deletes every cached environment.
+func ClearCache(opts CacheOptions) error {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(os.RemoveAll(opts.Dir))
+}
+
+type cacheEntry struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Vars      []EnvVar  `json:"vars"`
+}
+
+func (c *CachedStore) List(ctx context.Context, envID EnvID) ([]EnvVar, error) {
+	entry, err := c.read(envID)
+	if c.opts.Offline {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errors.Wrapf(ErrNotCached, "environment %s", envID.EnvName)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return entry.Vars, nil
+	}
+	// A cache that can't be read is treated as a miss: the store is the
+	// source of truth and the entry is rewritten below.
+	if err == nil && time.Since(entry.FetchedAt) < c.opts.TTL {
+		return entry.Vars, nil
+	}
+
+	vars, err := c.store.List(ctx, envID)
+	if err != nil {
+		return nil, err
+	}
+	// The variables were fetched, so a cache that can't be written only
+	// costs the next call a fetch.
+	if err := c.write(envID, &cacheEntry{FetchedAt: time.Now(), Vars: vars}); err != nil {
+		c.logf("envsec: failed to cache variables of environment %s: %v\n", envID.EnvName, err)
+	}
+	return vars, nil
+}
+
+func (c *CachedStore) Get(ctx context.Context, envID EnvID, name string) (string, error) {
+	if !c.opts.Offline {
+		return c.store.Get(ctx, envID, name)
+	}
+	vars, err := c.List(ctx, envID)
+	if err != nil {
+		return "", err
+	}
+	for _, v := range vars {
+		if v.Name == name {
+			return v.Value, nil
+		}
+	}
+	return "", KeyNotFound(name)
+}
+
+func (c *CachedStore) GetAll(ctx context.Context, envID EnvID, names []string) ([]EnvVar, error) {
+	if !c.opts.Offline {
+		return c.store.GetAll(ctx, envID, names)
+	}
+	vars, err := c.List(ctx, envID)
+	if err != nil {
+		return nil, err
+	}
+	result := []EnvVar{}
+	for _, v := range vars {
+		for _, name := range names {
+			if v.Name == name {
+				result = append(result, v)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (c *CachedStore) Set(ctx context.Context, envID EnvID, name string, value string) error {
+	return c.change(envID, func() error {
+		return c.store.Set(ctx, envID, name, value)
+	})
+}
+
+func (c *CachedStore) SetAll(ctx context.Context, envID EnvID, values map[string]string) error {
+	return c.change(envID, func() error {
+		return c.store.SetAll(ctx, envID, values)
+	})
+}
+
+func (c *CachedStore) Delete(ctx context.Context, envID EnvID, name string) error {
+	return c.change(envID, func() error {
+		return c.store.Delete(ctx, envID, name)
+	})
+}
+
+func (c *CachedStore) DeleteAll(ctx context.Context, envID EnvID, names []string) error {
+	return c.change(envID, func() error {
+		return c.store.DeleteAll(ctx, envID, names)
+	})
+}
+
+// ListEnvNames isn't cached: it always asks the wrapped store.
+func (c *CachedStore) ListEnvNames(ctx context.Context, envID EnvID) ([]string, error) {
+	return ListEnvNames(ctx, c.store, envID)
+}
+
+// ListNames forwards to the wrapped store like Get and GetAll, and lists the
+// cached variables while offline.
+func (c *CachedStore) ListNames(ctx context.Context, envID EnvID) ([]string, error) {
+	if !c.opts.Offline {
+		return ListNames(ctx, c.store, envID)
+	}
+	vars, err := c.List(ctx, envID)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(vars))
+	for _, v := range vars {
+		names = append(names, v.Name)
+	}
+	return names, nil
+}
+
+// History isn't cached either: it always asks the wrapped store.
+func (c *CachedStore) History(ctx context.Context, envID EnvID, name string) ([]Version, error) {
+	return History(ctx, c.store, envID, name)
+}
+
+// change runs a write against the wrapped store and then forgets the cached
+// copy of envID, even if the write failed part way through.
+func (c *CachedStore) change(envID EnvID, write func() error) error {
+	if c.opts.Offline {
+		return errors.New("can't change variables while offline")
+	}
+	err := write()
+	if rmErr := os.Remove(c.path(envID)); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) && err == nil {
+		err = errors.WithStack(rmErr)
+	}
+	return err
+}
+
+func (c *CachedStore) path(envID EnvID) string {
+	sum := sha256.Sum256([]byte(envID.OrgID + "/" + envID.ProjectID + "/" + envID.EnvName))
+	return filepath.Join(c.opts.Dir, hex.EncodeToString(sum[:]))
+}
+
+func (c *CachedStore) read(envID EnvID) (*cacheEntry, error) {
+	sealed, err := os.ReadFile(c.path(envID))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("cache file is corrupted")
+	}
+	data, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt cache file")
+	}
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return entry, nil
+}
+
+func (c *CachedStore) write(envID EnvID, entry *cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.MkdirAll(c.opts.Dir, 0o700); err != nil {
+		return errors.WithStack(err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, data, nil)
+	return errors.WithStack(os.WriteFile(c.path(envID), sealed, 0o600))
+}
+
+func logToStderr(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format, args...)
+}
+
+// loadOrCreateCacheKey reads the AES-256 key from path, generating and saving
+// a random one if the file doesn't exist yet.
+func loadOrCreateCacheKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, errors.Errorf("cache key %s has the wrong length", path)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, errors.WithStack(err)
+	}
+
+	key = make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return key, errors.WithStack(os.WriteFile(path, key, 0o600))
+}
@@ -0,0 +1,263 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envsec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// countingStore keeps the variables of a single environment and counts the
+// calls to List.
+type countingStore struct {
+	Store
+	vars  map[string]string
+	lists int
+}
+
+func (s *countingStore) List(ctx context.Context, envID EnvID) ([]EnvVar, error) {
+	s.lists++
+	return SortedEnvVars(s.vars), nil
+}
+
+func (s *countingStore) Set(ctx context.Context, envID EnvID, name string, value string) error {
+	s.vars[name] = value
+	return nil
+}
+
+func (s *countingStore) SetAll(ctx context.Context, envID EnvID, values map[string]string) error {
+	for name, value := range values {
+		s.vars[name] = value
+	}
+	return nil
+}
+
+func (s *countingStore) Delete(ctx context.Context, envID EnvID, name string) error {
+	delete(s.vars, name)
+	return nil
+}
+
+// newTestCachedStore returns a CachedStore in front of a countingStore, with
+// its cache and key in dir.
+func newTestCachedStore(t *testing.T, dir string, opts CacheOptions) (*CachedStore, *countingStore) {
+	t.Helper()
+	store := &countingStore{vars: map[string]string{"FOO": "bar"}}
+	if opts.Dir == "" {
+		opts.Dir = filepath.Join(dir, "vars")
+	}
+	if opts.KeyFile == "" {
+		opts.KeyFile = filepath.Join(dir, "cache.key")
+	}
+	c, err := NewCachedStore(store, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, store
+}
+
+var testEnvID = EnvID{OrgID: "org", ProjectID: "proj", EnvName: "dev"}
+
+func TestCachedStoreTTL(t *testing.T) {
+	tests := []struct {
+		name          string
+		ttl           time.Duration
+		fetchedAgo    time.Duration
+		expectedLists int
+	}{
+		{"fresh entry is used", time.Minute, time.Second, 1},
+		{"expired entry is refetched", time.Minute, 2 * time.Minute, 2},
+		{"zero TTL always refetches", 0, 0, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, store := newTestCachedStore(t, t.TempDir(), CacheOptions{TTL: test.ttl})
+			ctx := context.Background()
+			if _, err := c.List(ctx, testEnvID); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			// Backdate the entry instead of waiting for it to expire.
+			entry, err := c.read(testEnvID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry.FetchedAt = time.Now().Add(-test.fetchedAgo)
+			if err := c.write(testEnvID, entry); err != nil {
+				t.Fatal(err)
+			}
+
+			vars, err := c.List(ctx, testEnvID)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(vars) != 1 || vars[0].Value != "bar" {
+				t.Errorf("Expected FOO=bar, but got %v", vars)
+			}
+			if store.lists != test.expectedLists {
+				t.Errorf("Expected %d calls to List, but got %d", test.expectedLists, store.lists)
+			}
+		})
+	}
+}
+
+func TestCachedStoreOffline(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	offline, store := newTestCachedStore(t, dir, CacheOptions{Offline: true})
+
+	if _, err := offline.List(ctx, testEnvID); !errors.Is(err, ErrNotCached) {
+		t.Errorf("Expected ErrNotCached before caching, but got %v", err)
+	}
+
+	online, _ := newTestCachedStore(t, dir, CacheOptions{TTL: time.Minute})
+	if _, err := online.List(ctx, testEnvID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	value, err := offline.Get(ctx, testEnvID, "FOO")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if value != "bar" {
+		t.Errorf("Expected %q, but got %q", "bar", value)
+	}
+	names, err := offline.ListNames(ctx, testEnvID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(names) != 1 || names[0] != "FOO" {
+		t.Errorf("Expected [FOO], but got %v", names)
+	}
+	if _, err := offline.Get(ctx, testEnvID, "MISSING"); err == nil {
+		t.Error("Expected an error for a variable that isn't cached")
+	}
+	if err := offline.Set(ctx, testEnvID, "FOO", "baz"); err == nil {
+		t.Error("Expected an error for a change while offline")
+	}
+	if store.lists != 0 {
+		t.Errorf("Expected the offline store not to be called, but List was called %d times", store.lists)
+	}
+}
+
+func TestCachedStoreOfflineWithoutStore(t *testing.T) {
+	dir := t.TempDir()
+	online, _ := newTestCachedStore(t, dir, CacheOptions{TTL: time.Minute})
+	if _, err := online.List(context.Background(), testEnvID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	offline, err := NewCachedStore(nil, CacheOptions{
+		Dir:     filepath.Join(dir, "vars"),
+		KeyFile: filepath.Join(dir, "cache.key"),
+		Offline: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars, err := offline.List(context.Background(), testEnvID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(vars) != 1 || vars[0].Value != "bar" {
+		t.Errorf("Expected FOO=bar, but got %v", vars)
+	}
+}
+
+func TestCachedStoreChangesInvalidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(ctx context.Context, c *CachedStore) error
+		expected int
+	}{
+		{"Set", func(ctx context.Context, c *CachedStore) error {
+			return c.Set(ctx, testEnvID, "FOO", "baz")
+		}, 1},
+		{"SetAll", func(ctx context.Context, c *CachedStore) error {
+			return c.SetAll(ctx, testEnvID, map[string]string{"FOO": "baz", "BAR": "qux"})
+		}, 2},
+		{"Delete", func(ctx context.Context, c *CachedStore) error {
+			return c.Delete(ctx, testEnvID, "FOO")
+		}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, store := newTestCachedStore(t, t.TempDir(), CacheOptions{TTL: time.Hour})
+			ctx := context.Background()
+			if _, err := c.List(ctx, testEnvID); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := test.change(ctx, c); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			vars, err := c.List(ctx, testEnvID)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if store.lists != 2 {
+				t.Errorf("Expected the change to drop the cached copy, but List was called %d times", store.lists)
+			}
+			if len(vars) != test.expected {
+				t.Errorf("Expected %d variables, but got %v", test.expected, vars)
+			}
+		})
+	}
+}
+
+func TestCachedStoreWrongKey(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	c, _ := newTestCachedStore(t, dir, CacheOptions{TTL: time.Hour})
+	if _, err := c.List(ctx, testEnvID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The same cache directory read with another machine's key.
+	otherKey := filepath.Join(dir, "other.key")
+	offline, _ := newTestCachedStore(t, dir, CacheOptions{KeyFile: otherKey, Offline: true})
+	if _, err := offline.List(ctx, testEnvID); err == nil || errors.Is(err, ErrNotCached) {
+		t.Errorf("Expected a decryption error, but got %v", err)
+	}
+
+	// Online, an entry that can't be decrypted is a miss and is refetched.
+	online, store := newTestCachedStore(t, dir, CacheOptions{KeyFile: otherKey, TTL: time.Hour})
+	vars, err := online.List(ctx, testEnvID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(vars) != 1 || store.lists != 1 {
+		t.Errorf("Expected 1 variable from 1 call to List, but got %v from %d", vars, store.lists)
+	}
+}
+
+func TestCachedStoreListIgnoresWriteErrors(t *testing.T) {
+	dir := t.TempDir()
+	// A file where the cache directory should be makes every write fail.
+	blocked := filepath.Join(dir, "blocked")
+	if err := os.WriteFile(blocked, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := newTestCachedStore(t, dir, CacheOptions{Dir: filepath.Join(blocked, "vars"), TTL: time.Hour})
+	logged := []string{}
+	c.logf = func(format string, args ...any) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+
+	vars, err := c.List(context.Background(), testEnvID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(vars) != 1 || vars[0].Value != "bar" {
+		t.Errorf("Expected FOO=bar, but got %v", vars)
+	}
+	if len(logged) != 1 {
+		t.Errorf("Expected the failed write to be logged once, but got %q", logged)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envsec
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+)
+
+// ConcurrentStore wraps a Store and fetches variables in parallel batches,
+// for stores that would otherwise fetch them one batch after another. Lists
+// are only parallelized if the wrapped store implements NameLister. Results
+// are sorted by name, whichever batch finishes first.
+type ConcurrentStore struct {
+	store   Store
+	workers int
+}
+
+// ConcurrentStore implements interface Store (compile-time check)
+var _ Store = (*ConcurrentStore)(nil)
+
+// ConcurrentStore implements interface EnvNameLister (compile-time check)
+var _ EnvNameLister = (*ConcurrentStore)(nil)
+
+// ConcurrentStore implements interface NameLister (compile-time check)
+var _ NameLister = (*ConcurrentStore)(nil)
+
+// ConcurrentStore implements interface Versioned (compile-time check)
+var _ Versioned = (*ConcurrentStore)(nil)
+
+// NewConcurrentStore returns a ConcurrentStore that makes at most workers
+// requests at a time.
+func NewConcurrentStore(store Store, workers int) *ConcurrentStore {
+	return &ConcurrentStore{
+		store:   store,
+		workers: max(workers, 1),
+	}
+}
+
+func (c *ConcurrentStore) List(ctx context.Context, envID EnvID) ([]EnvVar, error) {
+	if c.workers == 1 {
+		return c.store.List(ctx, envID)
+	}
+	names, err := ListNames(ctx, c.store, envID)
+	if errors.Is(err, ErrNamesUnsupported) {
+		return c.store.List(ctx, envID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.GetAll(ctx, envID, names)
+}
+
+func (c *ConcurrentStore) ListEnvNames(ctx context.Context, envID EnvID) ([]string, error) {
+	return ListEnvNames(ctx, c.store, envID)
+}
+
+// ListNames forwards to the wrapped store, so that callers can fetch the
+// variables in batches of their own.
+func (c *ConcurrentStore) ListNames(ctx context.Context, envID EnvID) ([]string, error) {
+	return ListNames(ctx, c.store, envID)
+}
+
+func (c *ConcurrentStore) History(ctx context.Context, envID EnvID, name string) ([]Version, error) {
+	return History(ctx, c.store, envID, name)
+}
+
+func (c *ConcurrentStore) Get(ctx context.Context, envID EnvID, name string) (string, error) {
+	return c.store.Get(ctx, envID, name)
+}
+
+// GetAll splits names into one batch per worker and fetches the batches in
+// parallel. If any of them fails, the others are cancelled and the first
+// error is returned.
+func (c *ConcurrentStore) GetAll(ctx context.Context, envID EnvID, names []string) ([]EnvVar, error) {
+	if c.workers == 1 || len(names) <= 1 {
+		return c.store.GetAll(ctx, envID, names)
+	}
+	batchSize := (len(names) + c.workers - 1) / c.workers
+	batches := lo.Chunk(names, batchSize)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([][]EnvVar, len(batches))
+	var firstErr error
+	var once sync.Once
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		wg.Add(1)
+		go func(i int, batch []string) {
+			defer wg.Done()
+			vars, err := c.store.GetAll(ctx, envID, batch)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = vars
+		}(i, batch)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	envVars := lo.Flatten(results)
+	sort(envVars)
+	return envVars, nil
+}
+
+func (c *ConcurrentStore) Set(ctx context.Context, envID EnvID, name string, value string) error {
+	return c.store.Set(ctx, envID, name, value)
+}
+
+func (c *ConcurrentStore) SetAll(ctx context.Context, envID EnvID, values map[string]string) error {
+	return c.store.SetAll(ctx, envID, values)
+}
+
+func (c *ConcurrentStore) Delete(ctx context.Context, envID EnvID, name string) error {
+	return c.store.Delete(ctx, envID, name)
+}
+
+func (c *ConcurrentStore) DeleteAll(ctx context.Context, envID EnvID, names []string) error {
+	return c.store.DeleteAll(ctx, envID, names)
+}
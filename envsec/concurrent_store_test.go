@@ -0,0 +1,93 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envsec
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// batchStore serves each GetAll batch after a delay that is longer for
+// earlier batches, so that they finish in reverse order.
+type batchStore struct {
+	Store
+	names   []string
+	fail    string
+	calls   atomic.Int32
+	running atomic.Int32
+	maxRun  atomic.Int32
+}
+
+func (s *batchStore) ListNames(ctx context.Context, envID EnvID) ([]string, error) {
+	return s.names, nil
+}
+
+func (s *batchStore) GetAll(ctx context.Context, envID EnvID, names []string) ([]EnvVar, error) {
+	s.calls.Add(1)
+	running := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		current := s.maxRun.Load()
+		if running <= current || s.maxRun.CompareAndSwap(current, running) {
+			break
+		}
+	}
+
+	time.Sleep(time.Duration(len(s.names)-indexOf(s.names, names[0])) * time.Millisecond)
+	vars := []EnvVar{}
+	for _, name := range names {
+		if name == s.fail {
+			return nil, errors.Errorf("failed to get %s", name)
+		}
+		vars = append(vars, EnvVar{Name: name, Value: "value of " + name})
+	}
+	return vars, nil
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestConcurrentStoreList(t *testing.T) {
+	names := []string{}
+	for i := 0; i < 20; i++ {
+		names = append(names, fmt.Sprintf("VAR_%02d", 19-i))
+	}
+	store := &batchStore{names: names}
+
+	vars, err := NewConcurrentStore(store, 4).List(context.Background(), EnvID{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(vars) != len(names) {
+		t.Fatalf("Expected %d variables, but got %d", len(names), len(vars))
+	}
+	for i, v := range vars {
+		if expected := fmt.Sprintf("VAR_%02d", i); v.Name != expected {
+			t.Errorf("Expected %s at position %d, but got %s", expected, i, v.Name)
+		}
+	}
+	if calls, maxRun := store.calls.Load(), store.maxRun.Load(); calls != 4 || maxRun > 4 {
+		t.Errorf("Expected 4 batches with at most 4 at a time, but got %d batches and %d at once", calls, maxRun)
+	}
+}
+
+func TestConcurrentStoreReturnsFirstError(t *testing.T) {
+	store := &batchStore{names: []string{"A", "B", "C", "D"}, fail: "C"}
+
+	_, err := NewConcurrentStore(store, 2).List(context.Background(), EnvID{})
+	if err == nil || err.Error() != "failed to get C" {
+		t.Errorf("Expected the batch's error, but got %v", err)
+	}
+}
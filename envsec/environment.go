@@ -0,0 +1,168 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envsec
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Where a variable in an Environment came from.
+const (
+	SourceLocal  = "local"
+	SourceRemote = "remote"
+)
+
+// EnvEntry is one variable of an Environment.
+type EnvEntry struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// Environment is an ordered set of variables for a process to run with.
+// Setting a name that is already present replaces its value in place, so the
+// last write wins. Names are case-insensitive on Windows.
+type Environment struct {
+	entries []EnvEntry
+	index   map[string]int
+}
+
+func NewEnvironment() *Environment {
+	return &Environment{index: map[string]int{}}
+}
+
+// LocalEnvironment returns the environment the current process is running
+// with.
+func LocalEnvironment() *Environment {
+	env := NewEnvironment()
+	for _, kv := range os.Environ() {
+		// Start looking for '=' after the first byte, because Windows keeps
+		// per-drive directories in variables such as "=C:=C:\dir".
+		i := strings.Index(kv[min(1, len(kv)):], "=")
+		if i < 0 {
+			continue
+		}
+		i += min(1, len(kv))
+		env.Set(kv[:i], kv[i+1:], SourceLocal)
+	}
+	return env
+}
+
+func (e *Environment) Set(name, value, source string) {
+	entry := EnvEntry{Name: name, Value: value, Source: source}
+	if i, ok := e.index[envKey(name)]; ok {
+		e.entries[i] = entry
+		return
+	}
+	e.index[envKey(name)] = len(e.entries)
+	e.entries = append(e.entries, entry)
+}
+
+func (e *Environment) Get(name string) (string, bool) {
+	i, ok := e.index[envKey(name)]
+	if !ok {
+		return "", false
+	}
+	return e.entries[i].Value, true
+}
+
+func (e *Environment) Has(name string) bool {
+	_, ok := e.index[envKey(name)]
+	return ok
+}
+
+// Entries returns a copy of the variables in the order they were first set.
+func (e *Environment) Entries() []EnvEntry {
+	return append([]EnvEntry{}, e.entries...)
+}
+
+// Environ returns the variables in the NAME=VALUE form used by exec.Cmd.Env.
+// The result is never nil: a nil Env makes exec.Cmd inherit the current
+// process's environment.
+func (e *Environment) Environ() []string {
+	env := make([]string, 0, len(e.entries))
+	for _, entry := range e.entries {
+		env = append(env, entry.Name+"="+entry.Value)
+	}
+	return env
+}
+
+// Validate checks that every variable can be passed to a process. Values may
+// span several lines, but neither names nor values can contain a NUL byte,
+// which the operating system uses to end each entry.
+func (e *Environment) Validate() error {
+	for _, entry := range e.entries {
+		if strings.ContainsRune(entry.Name, 0) || strings.ContainsRune(entry.Value, 0) {
+			return errors.Errorf(
+				"variable %q contains a NUL byte, which can't be passed in an environment",
+				entry.Name,
+			)
+		}
+	}
+	return nil
+}
+
+// ResolveOptions configures Resolve.
+type ResolveOptions struct {
+	// Start from an empty environment instead of the current process's.
+	NoInherit bool
+	// Names of variables that are inherited even with NoInherit.
+	Keep []string
+	// Keep inherited variables instead of replacing them with stored ones of
+	// the same name.
+	PreferLocal bool
+}
+
+// Resolve builds the environment for a process from stored variables. They
+// are applied over the current process's environment, so they win when a
+// name is defined in both, unless opts says otherwise.
+func Resolve(envVars []EnvVar, opts ResolveOptions) *Environment {
+	env := LocalEnvironment()
+	if opts.NoInherit {
+		local := env
+		env = NewEnvironment()
+		for _, name := range opts.Keep {
+			if value, ok := local.Get(name); ok {
+				env.Set(name, value, SourceLocal)
+			}
+		}
+	}
+	for _, envVar := range envVars {
+		if opts.PreferLocal && env.Has(envVar.Name) {
+			continue
+		}
+		env.Set(envVar.Name, envVar.Value, SourceRemote)
+	}
+	return env
+}
+
+// ResolvedEnv returns the environment to run a process with the variables
+// stored for id: the current process's environment with the stored variables
+// on top, as NAME=VALUE entries ready for exec.Cmd.Env. It fails if a
+// variable can't be passed to a process, as described in Validate.
+func ResolvedEnv(ctx context.Context, id EnvID, store Store) ([]string, error) {
+	envVars, err := store.List(ctx, id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	env := Resolve(envVars, ResolveOptions{})
+	if err := env.Validate(); err != nil {
+		return nil, err
+	}
+	return env.Environ(), nil
+}
+
+// envKey normalizes a variable name for lookups. Names are case-insensitive
+// on Windows.
+func envKey(name string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToUpper(name)
+	}
+	return name
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envsec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// memStore is a Store holding the variables of a single environment.
+type memStore struct {
+	Store
+	vars []EnvVar
+}
+
+func (s *memStore) List(ctx context.Context, envID EnvID) ([]EnvVar, error) {
+	return s.vars, nil
+}
+
+// TestHelperProcess isn't a real test. It is run as a child process by the
+// tests below and prints the variable named by ENVSEC_TEST_PRINT.
+func TestHelperProcess(t *testing.T) {
+	name := os.Getenv("ENVSEC_TEST_PRINT")
+	if name == "" {
+		return
+	}
+	fmt.Print(os.Getenv(name))
+	os.Exit(0)
+}
+
+func TestResolvedEnvMultiLineValues(t *testing.T) {
+	values := map[string]string{
+		"PEM":       "-----BEGIN KEY-----\nabc\ndef\n-----END KEY-----\n",
+		"CRLF":      "line one\r\nline two",
+		"LOOKALIKE": "first\nOTHER=injected",
+	}
+	store := &memStore{}
+	for name, value := range values {
+		store.vars = append(store.vars, EnvVar{Name: name, Value: value})
+	}
+
+	env, err := ResolvedEnv(context.Background(), EnvID{}, store)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for name, expected := range values {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+		cmd.Env = append(env, "ENVSEC_TEST_PRINT="+name)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Helper process failed: %v", err)
+		}
+		if string(out) != expected {
+			t.Errorf("%s: expected %q, but got %q", name, expected, out)
+		}
+	}
+	// A line that looks like an assignment must not become a variable.
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(env, "ENVSEC_TEST_PRINT=OTHER")
+	if out, err := cmd.Output(); err != nil || len(out) != 0 {
+		t.Errorf("Expected OTHER to be unset, but got %q (%v)", out, err)
+	}
+}
+
+func TestResolvedEnvRejectsNUL(t *testing.T) {
+	store := &memStore{vars: []EnvVar{{Name: "BAD", Value: "a\x00b"}}}
+	if _, err := ResolvedEnv(context.Background(), EnvID{}, store); err == nil {
+		t.Error("Expected an error for a value containing a NUL byte")
+	}
+}
@@ -6,6 +6,9 @@ package envsec
 import (
 	"context"
 	"path"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.jetpack.io/envsec/internal/build"
@@ -44,7 +47,8 @@ type Store interface {
 	Set(ctx context.Context, envID EnvID, name string, value string) error
 	// Set the values of multiple environment variables.
 	SetAll(ctx context.Context, envID EnvID, values map[string]string) error
-	// Get the value of an environment variable.
+	// Get the value of an environment variable. Returns ErrKeyNotFound if it
+	// isn't set.
 	Get(ctx context.Context, envID EnvID, name string) (string, error)
 	// Get the values of multiple environment variables.
 	GetAll(ctx context.Context, envID EnvID, names []string) ([]EnvVar, error)
@@ -54,9 +58,137 @@ type Store interface {
 	DeleteAll(ctx context.Context, envID EnvID, names []string) error
 }
 
+// EnvNameLister is implemented by stores that can list the environments of a
+// project.
+type EnvNameLister interface {
+	// List the names of the environments in envID's project that have at least
+	// one variable, sorted. envID.EnvName is ignored.
+	ListEnvNames(ctx context.Context, envID EnvID) ([]string, error)
+}
+
+var ErrEnvNamesUnsupported = errors.New("this store can't list environments")
+
+// ListEnvNames lists the environments of envID's project, or returns
+// ErrEnvNamesUnsupported if store doesn't implement EnvNameLister.
+func ListEnvNames(ctx context.Context, store Store, envID EnvID) ([]string, error) {
+	lister, ok := store.(EnvNameLister)
+	if !ok {
+		return nil, errors.WithStack(ErrEnvNamesUnsupported)
+	}
+	return lister.ListEnvNames(ctx, envID)
+}
+
+// NameLister is implemented by stores that can list the names of an
+// environment's variables without fetching their values.
+type NameLister interface {
+	// List the names of the variables associated with the given envID.
+	ListNames(ctx context.Context, envID EnvID) ([]string, error)
+}
+
+var ErrNamesUnsupported = errors.New("this store can't list variable names")
+
+// ListNames lists the names of envID's variables, or returns
+// ErrNamesUnsupported if store doesn't implement NameLister.
+func ListNames(ctx context.Context, store Store, envID EnvID) ([]string, error) {
+	lister, ok := store.(NameLister)
+	if !ok {
+		return nil, errors.WithStack(ErrNamesUnsupported)
+	}
+	return lister.ListNames(ctx, envID)
+}
+
+// Versioned is implemented by stores that keep the previous values of
+// variables.
+type Versioned interface {
+	// History returns every version of the variable name in envID, newest
+	// first. Returns ErrKeyNotFound if it isn't set.
+	History(ctx context.Context, envID EnvID, name string) ([]Version, error)
+}
+
+// Version is one of the values a variable has had.
+type Version struct {
+	// Number identifies the version. Later versions have higher numbers.
+	Number int64
+	Value  string
+	// When the version was written.
+	ModifiedAt time.Time
+	// Who wrote the version, if the store records it.
+	ModifiedBy string
+}
+
+var ErrVersionsUnsupported = errors.New("this store doesn't keep previous versions of variables")
+
+// History returns the versions of envID's variable name, or returns
+// ErrVersionsUnsupported if store doesn't implement Versioned.
+func History(ctx context.Context, store Store, envID EnvID, name string) ([]Version, error) {
+	versioned, ok := store.(Versioned)
+	if !ok {
+		return nil, errors.WithStack(ErrVersionsUnsupported)
+	}
+	return versioned.History(ctx, envID, name)
+}
+
 type EnvVar struct {
 	Name  string
 	Value string
+	// Whether Value is a secret, for stores that can tell.
+	Sensitivity Sensitivity
+}
+
+// IsSecret reports whether the variable's value should be masked when it is
+// displayed. Only values known to be plain configuration aren't.
+func (e EnvVar) IsSecret() bool {
+	return e.Sensitivity != SensitivityPlain
+}
+
+// SortedEnvVars returns the variables in vars, which maps names to values,
+// sorted by name.
+func SortedEnvVars(vars map[string]string) []EnvVar {
+	result := []EnvVar{}
+	for name, value := range vars {
+		result = append(result, EnvVar{Name: name, Value: value})
+	}
+	slices.SortFunc(result, func(a, b EnvVar) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return result
+}
+
+// Sensitivity says whether a value is a secret or plain configuration.
+type Sensitivity int
+
+const (
+	// The store doesn't say, so the value is treated as a secret.
+	SensitivityUnknown Sensitivity = iota
+	SensitivitySecret
+	// Plain configuration that is fine to display.
+	SensitivityPlain
+)
+
+var sensitivityNames = []string{"unknown", "secret", "plain"}
+
+func (s Sensitivity) String() string {
+	if s < 0 || int(s) >= len(sensitivityNames) {
+		return sensitivityNames[SensitivityUnknown]
+	}
+	return sensitivityNames[s]
+}
+
+// MarshalText encodes s by name, as in JSON output.
+func (s Sensitivity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a name written by MarshalText. Names it doesn't know
+// are read as SensitivityUnknown.
+func (s *Sensitivity) UnmarshalText(text []byte) error {
+	*s = SensitivityUnknown
+	for i, name := range sensitivityNames {
+		if string(text) == name {
+			*s = Sensitivity(i)
+		}
+	}
+	return nil
 }
 
 func NewStore(ctx context.Context, config Config) (Store, error) {
@@ -0,0 +1,83 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envsec
+
+import (
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/aws/smithy-go"
+	"github.com/pkg/errors"
+)
+
+// Classes of errors returned by stores, which callers can check for with
+// errors.Is whichever backend the store uses.
+var (
+	// The environment, or the project it belongs to, doesn't exist.
+	ErrEnvNotFound = errors.New("environment not found")
+	// The credentials are missing or expired, or don't allow the operation.
+	ErrUnauthorized = errors.New("unauthorized")
+	// The variable isn't set in the environment.
+	ErrKeyNotFound = errors.New("variable not found")
+)
+
+// AWS error codes that belong to one of the classes above.
+var awsErrorClasses = map[string]error{
+	"AccessDeniedException":       ErrUnauthorized,
+	"UnrecognizedClientException": ErrUnauthorized,
+	"InvalidSignatureException":   ErrUnauthorized,
+	"ExpiredTokenException":       ErrUnauthorized,
+	"ParameterNotFound":           ErrKeyNotFound,
+}
+
+// classifiedError is an error from a backend marked with its class. It keeps
+// the backend's message, and errors.As still finds the original error.
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.class, e.err}
+}
+
+// classifyError marks err with its class, if it belongs to one, so that
+// errors.Is(err, ErrUnauthorized) and the like work.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if class := errorClass(err); class != nil {
+		return &classifiedError{class: class, err: err}
+	}
+	return err
+}
+
+func errorClass(err error) error {
+	var connectErr *connect.Error
+	if errors.As(err, &connectErr) {
+		switch connectErr.Code() {
+		case connect.CodeUnauthenticated, connect.CodePermissionDenied:
+			return ErrUnauthorized
+		case connect.CodeNotFound:
+			return ErrEnvNotFound
+		}
+		return nil
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return awsErrorClasses[apiErr.ErrorCode()]
+	}
+	return nil
+}
+
+// KeyNotFound returns the error Get returns for a variable that isn't set:
+// ErrKeyNotFound, with the variable's name.
+func KeyNotFound(name string) error {
+	return errors.WithStack(fmt.Errorf("%w: %s", ErrKeyNotFound, name))
+}
@@ -0,0 +1,48 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envsec
+
+import (
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/aws/smithy-go"
+	"github.com/pkg/errors"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{"unauthenticated", connect.NewError(connect.CodeUnauthenticated, nil), ErrUnauthorized},
+		{"permission denied", connect.NewError(connect.CodePermissionDenied, nil), ErrUnauthorized},
+		{"project not found", connect.NewError(connect.CodeNotFound, nil), ErrEnvNotFound},
+		{"aws access denied", &smithy.GenericAPIError{Code: "AccessDeniedException"}, ErrUnauthorized},
+		{"aws parameter not found", errors.WithStack(&smithy.GenericAPIError{Code: "ParameterNotFound"}), ErrKeyNotFound},
+		{"unavailable", connect.NewError(connect.CodeUnavailable, nil), nil},
+		{"other", errors.New("boom"), nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := errors.WithStack(classifyError(test.err))
+			for _, class := range []error{ErrUnauthorized, ErrEnvNotFound, ErrKeyNotFound} {
+				if errors.Is(err, class) != (class == test.expected) {
+					t.Errorf("Expected errors.Is(err, %v) to be %v", class, class == test.expected)
+				}
+			}
+			if !errors.Is(err, test.err) {
+				t.Error("Expected the original error to be kept")
+			}
+			if err.Error() != test.err.Error() {
+				t.Errorf("Expected message %q, but got %q", test.err.Error(), err.Error())
+			}
+			if test.expected != nil && IsTransient(err) {
+				t.Error("Expected a classified error not to be transient")
+			}
+		})
+	}
+}
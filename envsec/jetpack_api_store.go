@@ -2,8 +2,10 @@ package envsec
 
 import (
 	"context"
+	"slices"
 
 	"connectrpc.com/connect"
+	"github.com/samber/lo"
 	"go.jetpack.io/pkg/api"
 	secretsv1alpha1 "go.jetpack.io/pkg/api/gen/priv/secrets/v1alpha1"
 	"go.jetpack.io/pkg/api/gen/priv/secrets/v1alpha1/secretsv1alpha1connect"
@@ -16,6 +18,9 @@ type JetpackAPIStore struct {
 // JetpackAPIStore implements interface Store (compile-time check)
 var _ Store = (*JetpackAPIStore)(nil)
 
+// JetpackAPIStore implements interface EnvNameLister (compile-time check)
+var _ EnvNameLister = (*JetpackAPIStore)(nil)
+
 func newJetpackAPIStore(ctx context.Context, config *JetpackAPIConfig) *JetpackAPIStore {
 	return &JetpackAPIStore{
 		client: api.NewClient(ctx, config.host, config.token).SecretsService(),
@@ -28,7 +33,7 @@ func (j JetpackAPIStore) List(ctx context.Context, envID EnvID) ([]EnvVar, error
 		connect.NewRequest(&secretsv1alpha1.ListSecretsRequest{ProjectId: envID.ProjectID}),
 	)
 	if err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 	result := []EnvVar{}
 	for _, secret := range resp.Msg.Secrets {
@@ -44,6 +49,27 @@ func (j JetpackAPIStore) List(ctx context.Context, envID EnvID) ([]EnvVar, error
 	return result, nil
 }
 
+func (j JetpackAPIStore) ListEnvNames(ctx context.Context, envID EnvID) ([]string, error) {
+	resp, err := j.client.ListSecrets(
+		ctx,
+		connect.NewRequest(&secretsv1alpha1.ListSecretsRequest{ProjectId: envID.ProjectID}),
+	)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	envNames := map[string]bool{}
+	for _, secret := range resp.Msg.Secrets {
+		for envName, v := range secret.EnvironmentValues {
+			if len(v) > 0 {
+				envNames[envName] = true
+			}
+		}
+	}
+	result := lo.Keys(envNames)
+	slices.Sort(result)
+	return result, nil
+}
+
 func (j JetpackAPIStore) Set(ctx context.Context, envID EnvID, name string, value string) error {
 	_, err := j.client.PatchSecret(
 		ctx, connect.NewRequest(
@@ -58,7 +84,7 @@ func (j JetpackAPIStore) Set(ctx context.Context, envID EnvID, name string, valu
 			},
 		),
 	)
-	return err
+	return classifyError(err)
 }
 
 func (j JetpackAPIStore) SetAll(ctx context.Context, envID EnvID, values map[string]string) error {
@@ -84,7 +110,7 @@ func (j JetpackAPIStore) SetAll(ctx context.Context, envID EnvID, values map[str
 	_, err := j.client.Batch(
 		ctx, connect.NewRequest(&secretsv1alpha1.BatchRequest{Actions: patchActions}),
 	)
-	return err
+	return classifyError(err)
 }
 
 func (j JetpackAPIStore) Get(ctx context.Context, envID EnvID, name string) (string, error) {
@@ -97,13 +123,13 @@ func (j JetpackAPIStore) Get(ctx context.Context, envID EnvID, name string) (str
 			return v.Value, nil
 		}
 	}
-	return "", nil
+	return "", KeyNotFound(name)
 }
 
 func (j JetpackAPIStore) GetAll(ctx context.Context, envID EnvID, names []string) ([]EnvVar, error) {
 	vars, err := j.List(ctx, envID)
 	if err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 	result := []EnvVar{}
 	for _, v := range vars {
@@ -126,7 +152,7 @@ func (j JetpackAPIStore) Delete(ctx context.Context, envID EnvID, name string) e
 			},
 		),
 	)
-	return err
+	return classifyError(err)
 }
 
 func (j JetpackAPIStore) DeleteAll(ctx context.Context, envID EnvID, names []string) error {
@@ -148,5 +174,5 @@ func (j JetpackAPIStore) DeleteAll(ctx context.Context, envID EnvID, names []str
 	_, err := j.client.Batch(
 		ctx, connect.NewRequest(&secretsv1alpha1.BatchRequest{Actions: deleteActions}),
 	)
-	return err
+	return classifyError(err)
 }
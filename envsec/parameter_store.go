@@ -5,6 +5,8 @@ package envsec
 
 import (
 	"context"
+	"path"
+	"slices"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -98,7 +100,9 @@ func (s *parameterStore) newParameter(ctx context.Context, param *parameter, val
 	return errors.WithStack(err)
 }
 
-// Updates a stored parameter.
+// Updates a stored parameter, keeping its type. A SecureString parameter is
+// encrypted with the configured KMS key, like in newParameter, so that
+// overwriting it also moves it to that key.
 func (s *parameterStore) overwriteParameterValue(ctx context.Context, v *parameter, value string) error {
 	input := &ssm.PutParameterInput{
 		Name:        aws.String(v.id),
@@ -106,10 +110,29 @@ func (s *parameterStore) overwriteParameterValue(ctx context.Context, v *paramet
 		Overwrite:   lo.ToPtr(true),
 		Value:       awsSSMParamStoreValue(value),
 	}
+	if s.config.KmsKeyID != "" {
+		paramType, err := s.parameterType(ctx, v.id)
+		if err != nil {
+			return err
+		}
+		if paramType == types.ParameterTypeSecureString {
+			input.Type = paramType
+			input.KeyId = aws.String(s.config.KmsKeyID)
+		}
+	}
 	_, err := s.client.PutParameter(ctx, input)
 	return errors.WithStack(err)
 }
 
+// parameterType returns the type of the parameter with the given id.
+func (s *parameterStore) parameterType(ctx context.Context, id string) (types.ParameterType, error) {
+	output, err := s.client.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(id)})
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return output.Parameter.Type, nil
+}
+
 func (s *parameterStore) listByPath(ctx context.Context, id EnvID) ([]EnvVar, error) {
 	// Create the request object:
 	req := &ssm.GetParametersByPathInput{
@@ -134,8 +157,9 @@ func (s *parameterStore) listByPath(ctx context.Context, id EnvID) ([]EnvVar, er
 		params := resp.Parameters
 		for _, p := range params {
 			results = append(results, EnvVar{
-				Name:  nameFromPath(aws.ToString(p.Name)),
-				Value: awsSSMParamStoreValueToString(p.Value),
+				Name:        nameFromPath(aws.ToString(p.Name)),
+				Value:       awsSSMParamStoreValueToString(p.Value),
+				Sensitivity: parameterSensitivity(p.Type),
 			})
 		}
 	}
@@ -144,10 +168,64 @@ func (s *parameterStore) listByPath(ctx context.Context, id EnvID) ([]EnvVar, er
 	return results, nil
 }
 
+// listEnvNamesByPath lists the environments that have parameters under the
+// project's path: <namespace>/<project-id>/<env-name>/<var-name>.
+func (s *parameterStore) listEnvNamesByPath(ctx context.Context, id EnvID) ([]string, error) {
+	projectPath := path.Join(s.config.pathNamespace(id), id.ProjectID)
+	req := &ssm.DescribeParametersInput{
+		ParameterFilters: []types.ParameterStringFilter{
+			{
+				Key:    lo.ToPtr("Path"),
+				Option: lo.ToPtr("Recursive"),
+				Values: []string{projectPath},
+			},
+		},
+	}
+
+	envNames := map[string]bool{}
+	paginator := ssm.NewDescribeParametersPaginator(s.client, req)
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, p := range resp.Parameters {
+			rest := strings.TrimPrefix(aws.ToString(p.Name), projectPath+"/")
+			if envName, _, ok := strings.Cut(rest, "/"); ok {
+				envNames[envName] = true
+			}
+		}
+	}
+	result := lo.Keys(envNames)
+	slices.Sort(result)
+	return result, nil
+}
+
 func (s *parameterStore) listByTags(ctx context.Context, envID EnvID) ([]EnvVar, error) {
+	varNames, err := s.listNames(ctx, envID)
+	if err != nil {
+		return []EnvVar{}, err
+	}
+	return s.getAll(ctx, envID, varNames)
+}
+
+// listNames lists the names of envID's parameters without their values.
+func (s *parameterStore) listNames(ctx context.Context, envID EnvID) ([]string, error) {
+	filters := s.buildFilters(envID)
+	if s.config.hasDefaultPaths() {
+		// All of the environment's parameters are under its path, whether or
+		// not they are tagged.
+		filters = []types.ParameterStringFilter{
+			{
+				Key:    lo.ToPtr("Path"),
+				Option: lo.ToPtr("Recursive"),
+				Values: []string{s.config.varPath(envID, "")},
+			},
+		}
+	}
 	// Create the request object:
 	req := &ssm.DescribeParametersInput{
-		ParameterFilters: s.buildFilters(envID),
+		ParameterFilters: filters,
 	}
 
 	varNames := []string{}
@@ -157,7 +235,7 @@ func (s *parameterStore) listByTags(ctx context.Context, envID EnvID) ([]EnvVar,
 		// Issue the request for the next page:
 		resp, err := paginator.NextPage(ctx)
 		if err != nil {
-			return []EnvVar{}, errors.WithStack(err)
+			return nil, errors.WithStack(err)
 		}
 		// Append results:
 		for _, p := range resp.Parameters {
@@ -166,8 +244,7 @@ func (s *parameterStore) listByTags(ctx context.Context, envID EnvID) ([]EnvVar,
 			varNames = append(varNames, varName)
 		}
 	}
-
-	return s.getAll(ctx, envID, varNames)
+	return varNames, nil
 }
 
 func (s *parameterStore) buildFilters(envID EnvID) []types.ParameterStringFilter {
@@ -227,8 +304,9 @@ func (s *parameterStore) getAll(ctx context.Context, envID EnvID, varNames []str
 		// Append results:
 		for _, p := range resp.Parameters {
 			results = append(results, EnvVar{
-				Name:  nameFromPath(aws.ToString(p.Name)),
-				Value: awsSSMParamStoreValueToString(p.Value),
+				Name:        nameFromPath(aws.ToString(p.Name)),
+				Value:       awsSSMParamStoreValueToString(p.Value),
+				Sensitivity: parameterSensitivity(p.Type),
 			})
 		}
 	}
@@ -236,6 +314,34 @@ func (s *parameterStore) getAll(ctx context.Context, envID EnvID, varNames []str
 	return results, nil
 }
 
+// history returns the versions of a parameter, newest first.
+func (s *parameterStore) history(ctx context.Context, envID EnvID, varName string) ([]Version, error) {
+	req := &ssm.GetParameterHistoryInput{
+		Name:           aws.String(s.config.varPath(envID, varName)),
+		WithDecryption: lo.ToPtr(true),
+	}
+
+	versions := []Version{}
+	paginator := ssm.NewGetParameterHistoryPaginator(s.client, req)
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, p := range resp.Parameters {
+			versions = append(versions, Version{
+				Number:     p.Version,
+				Value:      awsSSMParamStoreValueToString(p.Value),
+				ModifiedAt: aws.ToTime(p.LastModifiedDate),
+				ModifiedBy: aws.ToString(p.LastModifiedUser),
+			})
+		}
+	}
+	// AWS returns the oldest version first.
+	slices.Reverse(versions)
+	return versions, nil
+}
+
 func (s *parameterStore) deleteAll(ctx context.Context, envID EnvID, varNames []string) error {
 	paths := lo.Map(varNames, func(name string, _ int) string {
 		return s.config.varPath(envID, name)
@@ -308,3 +414,16 @@ func awsSSMParamStoreValueToString(s *string) string {
 	}
 	return *s
 }
+
+// parameterSensitivity tells secrets from plain configuration by the
+// parameter's type. envsec stores every variable as a SecureString, but
+// parameters created by other tools may be plain Strings.
+func parameterSensitivity(paramType types.ParameterType) Sensitivity {
+	switch paramType {
+	case types.ParameterTypeSecureString:
+		return SensitivitySecret
+	case types.ParameterTypeString, types.ParameterTypeStringList:
+		return SensitivityPlain
+	}
+	return SensitivityUnknown
+}
@@ -0,0 +1,36 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.jetpack.io/envsec"
+	"go.jetpack.io/envsec/internal/tux"
+)
+
+func cacheCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the local cache of environment variables",
+		Long: "Manage the encrypted local cache used by --cache-ttl and --offline. " +
+			"Cached variables are encrypted with a key that never leaves this machine.",
+	}
+	command.AddCommand(cacheClearCmd())
+	return command
+}
+
+func cacheClearCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear",
+		Short: "Delete all locally cached environment variables",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := envsec.ClearCache(envsec.CacheOptions{}); err != nil {
+				return errors.WithStack(err)
+			}
+			return tux.WriteHeader(cmd.OutOrStdout(), "[DONE] Cleared the local cache\n")
+		},
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const configFileName = ".envsec.json"
+
+// fileConfig is the environment selection a repository can commit in an
+// .envsec.json file, so commands run anywhere inside it need no flags.
+type fileConfig struct {
+	OrgID       string `json:"org_id,omitempty"`
+	ProjectID   string `json:"project_id,omitempty"`
+	Environment string `json:"environment,omitempty"`
+}
+
+// findFileConfig reads the nearest .envsec.json in dir or one of its parents.
+// It returns an empty config if there is none.
+func findFileConfig(dir string) (*fileConfig, error) {
+	for {
+		path := filepath.Join(dir, configFileName)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			cfg := &fileConfig{}
+			if err := json.Unmarshal(data, cfg); err != nil {
+				return nil, errors.Wrapf(err, "failed to parse %s", path)
+			}
+			return cfg, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, errors.WithStack(err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return &fileConfig{}, nil
+		}
+		dir = parent
+	}
+}
+
+// applyDefaults fills in the settings that weren't given as flags. Each one
+// comes from the first of these that sets it: the flag, its ENVSEC_*
+// environment variable, the nearest .envsec.json, and the flag's default.
+// It reports whether an environment was chosen by any of them.
+func (f *configFlags) applyDefaults(cmd *cobra.Command) (bool, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	file, err := findFileConfig(wd)
+	if err != nil {
+		return false, err
+	}
+
+	envSelected := false
+	for _, setting := range []struct {
+		flag      string
+		envVar    string
+		value     *string
+		fileValue string
+	}{
+		{"project-id", "ENVSEC_PROJECT_ID", &f.projectID, file.ProjectID},
+		{"org-id", "ENVSEC_ORG_ID", &f.orgID, file.OrgID},
+		{environmentFlagName, "ENVSEC_ENVIRONMENT", &f.envName, file.Environment},
+	} {
+		if cmd.Flags().Changed(setting.flag) {
+			envSelected = envSelected || setting.flag == environmentFlagName
+			continue
+		}
+		value := os.Getenv(setting.envVar)
+		if value == "" {
+			value = setting.fileValue
+		}
+		if value != "" {
+			*setting.value = value
+			envSelected = envSelected || setting.flag == environmentFlagName
+		}
+	}
+	return envSelected, nil
+}
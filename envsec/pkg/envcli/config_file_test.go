@@ -0,0 +1,62 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestApplyDefaultsPrecedence(t *testing.T) {
+	root := t.TempDir()
+	config := `{"org_id": "org_file", "project_id": "proj_file", "environment": "prod"}`
+	if err := os.WriteFile(filepath.Join(root, configFileName), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The file is found from a nested directory.
+	wd := filepath.Join(root, "nested", "dir")
+	if err := os.MkdirAll(wd, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(wd); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(oldWd) })
+
+	t.Setenv("ENVSEC_ORG_ID", "")
+	t.Setenv("ENVSEC_PROJECT_ID", "proj_env")
+	t.Setenv("ENVSEC_ENVIRONMENT", "staging")
+
+	flags := &configFlags{}
+	cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+	flags.register(cmd)
+	cmd.SetArgs([]string{"--environment", "preview"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	envSelected, err := flags.applyDefaults(cmd)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !envSelected {
+		t.Error("Expected an environment to be selected")
+	}
+	if flags.envName != "preview" {
+		t.Errorf("Expected the flag to win, but got environment %q", flags.envName)
+	}
+	if flags.projectID != "proj_env" {
+		t.Errorf("Expected the environment variable to win, but got project %q", flags.projectID)
+	}
+	if flags.orgID != "org_file" {
+		t.Errorf("Expected the config file value, but got org %q", flags.orgID)
+	}
+}
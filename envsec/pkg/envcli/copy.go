@@ -0,0 +1,102 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.jetpack.io/envsec"
+	"go.jetpack.io/envsec/internal/tux"
+)
+
+type copyCmdFlags struct {
+	configFlags
+	filterFlags
+	from      string
+	to        string
+	overwrite bool
+}
+
+func CopyCmd() *cobra.Command {
+	flags := &copyCmdFlags{}
+	command := &cobra.Command{
+		Use:   "copy --from <env> --to <env>",
+		Short: "Copy variables from one environment to another",
+		Long: "Copy the variables stored in one environment of a project into another. " +
+			"Variables that already exist in the destination are left unchanged unless --overwrite is set.",
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if flags.from == flags.to {
+				return errors.Errorf("cannot copy environment %s into itself", flags.from)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmdCfg, err := flags.genConfig(cmd)
+			if err != nil {
+				return err
+			}
+			fromID := envsec.EnvID{
+				OrgID:     cmdCfg.EnvID.OrgID,
+				ProjectID: cmdCfg.EnvID.ProjectID,
+				EnvName:   flags.from,
+			}
+			toID := envsec.EnvID{
+				OrgID:     cmdCfg.EnvID.OrgID,
+				ProjectID: cmdCfg.EnvID.ProjectID,
+				EnvName:   flags.to,
+			}
+
+			source, err := cmdCfg.Store.List(cmd.Context(), fromID)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			source, err = flags.filter(source)
+			if err != nil {
+				return err
+			}
+			existing, err := cmdCfg.Store.List(cmd.Context(), toID)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			sourceMap := envVarMap(source)
+			plan := planImport(envVarMap(existing), sourceMap, flags.overwrite)
+
+			toSet := map[string]string{}
+			for _, name := range append(plan.created, plan.updated...) {
+				toSet[name] = sourceMap[name]
+			}
+			if len(toSet) > 0 {
+				err = cmdCfg.Store.SetAll(cmd.Context(), toID, toSet)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+			}
+
+			return tux.WriteHeader(cmd.OutOrStdout(),
+				"[DONE] Copied %d environment variable(s) from %s to %s "+
+					"(%d created, %d overwritten, %d skipped)\n",
+				len(toSet),
+				strings.ToLower(flags.from),
+				strings.ToLower(flags.to),
+				len(plan.created),
+				len(plan.updated),
+				len(plan.skipped),
+			)
+		},
+	}
+
+	command.Flags().StringVar(&flags.from, "from", "", "Environment to copy variables from")
+	command.Flags().StringVar(&flags.to, "to", "", "Environment to copy variables to")
+	command.Flags().BoolVar(
+		&flags.overwrite, "overwrite", false, "Replace the values of variables that already exist in the destination")
+	_ = command.MarkFlagRequired("from")
+	_ = command.MarkFlagRequired("to")
+	flags.configFlags.register(command)
+	flags.filterFlags.register(command)
+
+	return command
+}
@@ -0,0 +1,169 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"go.jetpack.io/envsec"
+	"go.jetpack.io/envsec/internal/tux"
+)
+
+type diffCmdFlags struct {
+	configFlags
+	format     string
+	showValues bool
+}
+
+func DiffCmd() *cobra.Command {
+	flags := &diffCmdFlags{}
+	command := &cobra.Command{
+		Use:   "diff <envA> <envB>",
+		Short: "Show the differences between two environments",
+		Long: "Compare the variables stored in two environments of the same project. " +
+			"Lists the variables that exist only in <envA>, only in <envB>, and those " +
+			"set in both with different values. Values are masked unless --show-values is set.",
+		Args: cobra.ExactArgs(2),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if flags.format == "text" || flags.format == "json" {
+				return nil
+			}
+			return errors.Wrapf(errUnsupportedFormat, "format: %s", flags.format)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmdCfg, err := flags.genConfig(cmd)
+			if err != nil {
+				return err
+			}
+			envs := make([]map[string]string, len(args))
+			for i, envName := range args {
+				envID := envsec.EnvID{
+					OrgID:     cmdCfg.EnvID.OrgID,
+					ProjectID: cmdCfg.EnvID.ProjectID,
+					EnvName:   envName,
+				}
+				envVars, err := cmdCfg.Store.List(cmd.Context(), envID)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				envs[i] = envVarMap(envVars)
+			}
+
+			diff := diffEnvs(args[0], args[1], envs[0], envs[1])
+			if !flags.showValues {
+				diff.mask()
+			}
+			if flags.format == "json" {
+				return diff.writeJSON(cmd.OutOrStdout())
+			}
+			return diff.writeText(cmd.OutOrStdout())
+		},
+	}
+
+	command.Flags().StringVarP(
+		&flags.format, "format", "f", "text", "Output format: text or json")
+	command.Flags().BoolVarP(
+		&flags.showValues, "show-values", "s", false, "Display the values that differ (secrets included)")
+	flags.configFlags.register(command)
+
+	return command
+}
+
+type changedValue struct {
+	A string `json:"a"`
+	B string `json:"b"`
+}
+
+type envDiff struct {
+	EnvA    string                  `json:"env_a"`
+	EnvB    string                  `json:"env_b"`
+	OnlyInA map[string]string       `json:"only_in_a"`
+	OnlyInB map[string]string       `json:"only_in_b"`
+	Changed map[string]changedValue `json:"changed"`
+}
+
+func diffEnvs(nameA, nameB string, a, b map[string]string) *envDiff {
+	diff := &envDiff{
+		EnvA:    nameA,
+		EnvB:    nameB,
+		OnlyInA: map[string]string{},
+		OnlyInB: map[string]string{},
+		Changed: map[string]changedValue{},
+	}
+	for name, valueA := range a {
+		valueB, ok := b[name]
+		if !ok {
+			diff.OnlyInA[name] = valueA
+		} else if valueA != valueB {
+			diff.Changed[name] = changedValue{A: valueA, B: valueB}
+		}
+	}
+	for name, valueB := range b {
+		if _, ok := a[name]; !ok {
+			diff.OnlyInB[name] = valueB
+		}
+	}
+	return diff
+}
+
+// mask hides the values in d. It is applied after comparing so that values
+// which mask the same way are still reported as different.
+func (d *envDiff) mask() {
+	for name, value := range d.OnlyInA {
+		d.OnlyInA[name] = maskValue(value)
+	}
+	for name, value := range d.OnlyInB {
+		d.OnlyInB[name] = maskValue(value)
+	}
+	for name, change := range d.Changed {
+		d.Changed[name] = changedValue{A: maskValue(change.A), B: maskValue(change.B)}
+	}
+}
+
+func (d *envDiff) writeJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return errors.WithStack(err)
+}
+
+// writeText prints one line per differing variable, sorted by name: "-" for
+// variables only in A, "+" for those only in B and "~" for changed values.
+func (d *envDiff) writeText(w io.Writer) error {
+	if len(d.OnlyInA)+len(d.OnlyInB)+len(d.Changed) == 0 {
+		return tux.WriteHeader(w, "[DONE] Environments %s and %s are identical\n", d.EnvA, d.EnvB)
+	}
+
+	removed := color.New(color.FgRed).SprintfFunc()
+	added := color.New(color.FgGreen).SprintfFunc()
+	changed := color.New(color.FgYellow).SprintfFunc()
+
+	fmt.Fprintln(w, removed("--- %s", d.EnvA))
+	fmt.Fprintln(w, added("+++ %s", d.EnvB))
+	names := append(append(lo.Keys(d.OnlyInA), lo.Keys(d.OnlyInB)...), lo.Keys(d.Changed)...)
+	slices.Sort(names)
+	for _, name := range names {
+		var line string
+		if value, ok := d.OnlyInA[name]; ok {
+			line = removed("- %s=%s", name, value)
+		} else if value, ok := d.OnlyInB[name]; ok {
+			line = added("+ %s=%s", name, value)
+		} else {
+			line = changed("~ %s: %s -> %s", name, d.Changed[name].A, d.Changed[name].B)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"context"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.jetpack.io/envsec"
+)
+
+// sourceFile marks variables read from an --env-file.
+const sourceFile = "file"
+
+// sourceFlag marks variables given with --set.
+const sourceFlag = "flag"
+
+// optionalEnvFileSuffix marks an --env-file that is skipped if missing.
+const optionalEnvFileSuffix = "?"
+
+// expandRemote expands ${NAME} references in the values of remote variables
+// against the whole environment. Local values are never expanded, only
+// substituted.
+func expandRemote(env *envsec.Environment) error {
+	remote := map[string]string{}
+	for _, entry := range env.Entries() {
+		if entry.Source == envsec.SourceRemote {
+			remote[entry.Name] = entry.Value
+		}
+	}
+	expanded, err := expandReferences(remote, env.Get)
+	if err != nil {
+		return err
+	}
+	for name, value := range expanded {
+		env.Set(name, value, envsec.SourceRemote)
+	}
+	return nil
+}
+
+// applyEnvFiles sets the variables read from each --env-file, in order, so
+// they win over inherited and remote variables and later files win over
+// earlier ones.
+func (f *execCmdFlags) applyEnvFiles(env *envsec.Environment) error {
+	for _, path := range f.envFiles {
+		optional := strings.HasSuffix(path, optionalEnvFileSuffix)
+		path = strings.TrimSuffix(path, optionalEnvFileSuffix)
+		vars, err := godotenv.Read(path)
+		if optional && errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		names := lo.Keys(vars)
+		slices.Sort(names)
+		for _, name := range names {
+			env.Set(name, vars[name], sourceFile)
+		}
+	}
+	return nil
+}
+
+// applyOverrides sets the variables given with --set, which win over all
+// others.
+func (f *execCmdFlags) applyOverrides(env *envsec.Environment) error {
+	for _, override := range f.overrides {
+		name, value, ok := strings.Cut(override, "=")
+		if !ok || name == "" {
+			return errors.Errorf("--set %s must be of the form NAME=VALUE", override)
+		}
+		env.Set(name, value, sourceFlag)
+	}
+	return nil
+}
+
+// listMerged lists the stored variables of each of envIDs and merges them.
+// Where a name is set in several environments, the value from the last one
+// wins.
+func listMerged(
+	ctx context.Context,
+	store envsec.Store,
+	envIDs []envsec.EnvID,
+) ([]envsec.EnvVar, error) {
+	merged := []envsec.EnvVar{}
+	index := map[string]int{}
+	for _, envID := range envIDs {
+		envVars, err := store.List(ctx, envID)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, envVar := range envVars {
+			if i, ok := index[envVar.Name]; ok {
+				merged[i] = envVar
+				continue
+			}
+			index[envVar.Name] = len(merged)
+			merged = append(merged, envVar)
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.jetpack.io/envsec"
+)
+
+type envsCmdFlags struct {
+	configFlags
+	format string
+}
+
+func EnvsCmd() *cobra.Command {
+	flags := &envsCmdFlags{}
+	command := &cobra.Command{
+		Use:   "envs",
+		Short: "List the environments of the project",
+		Long: "List the names of the environments in the project that have at least one variable, one per line, " +
+			"or as a JSON array with --format json. These are the names --environment accepts.",
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if flags.format == "text" || flags.format == "json" {
+				return nil
+			}
+			return errors.Wrapf(errUnsupportedFormat, "format: %s", flags.format)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmdCfg, err := flags.genConfig(cmd)
+			if err != nil {
+				return err
+			}
+			envNames, err := envsec.ListEnvNames(cmd.Context(), cmdCfg.Store, cmdCfg.EnvID)
+			if errors.Is(err, envsec.ErrEnvNamesUnsupported) {
+				return errors.New("the store envsec is using can't list the environments of a project")
+			}
+			if err != nil {
+				return explainStoreError(errors.WithStack(err), cmdCfg.EnvID)
+			}
+
+			if flags.format == "json" {
+				data, err := json.MarshalIndent(envNames, "", "  ")
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+				return errors.WithStack(err)
+			}
+			if len(envNames) == 0 {
+				return nil
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), strings.Join(envNames, "\n"))
+			return errors.WithStack(err)
+		},
+	}
+
+	command.Flags().StringVarP(
+		&flags.format, "format", "f", "text", "Output format: text or json")
+	flags.configFlags.register(command)
+
+	return command
+}
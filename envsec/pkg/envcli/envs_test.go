@@ -0,0 +1,48 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"testing"
+
+	"go.jetpack.io/envsec"
+	"go.jetpack.io/envsec/pkg/memstore"
+)
+
+func TestEnvs(t *testing.T) {
+	envID := envsec.EnvID{ProjectID: "proj", OrgID: "org", EnvName: "dev"}
+	prod := envsec.EnvID{ProjectID: "proj", OrgID: "org", EnvName: "prod"}
+	other := envsec.EnvID{ProjectID: "other", OrgID: "org", EnvName: "staging"}
+	store := memstore.NewWithVars(map[envsec.EnvID]map[string]string{
+		envID: {"A": "1"},
+		prod:  {"A": "2"},
+		other: {"A": "3"},
+	})
+
+	tests := []struct {
+		name     string
+		store    envsec.Store
+		args     []string
+		expected string
+		err      bool
+	}{
+		{"text", store, nil, "dev\nprod\n", false},
+		{"json", store, []string{"--format", "json"}, "[\n  \"dev\",\n  \"prod\"\n]\n", false},
+		{"empty json", memstore.New(), []string{"--format", "json"}, "[]\n", false},
+		// Hide ListEnvNames from envs.
+		{"unsupported", struct{ envsec.Store }{store}, nil, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, _, err := runWithStore(t, test.store, envID, EnvsCmd(), test.args...)
+			if (err != nil) != test.err {
+				t.Fatalf("Expected an error: %v, but got %v", test.err, err)
+			}
+			if out != test.expected {
+				t.Errorf("Expected %q, but got %q", test.expected, out)
+			}
+		})
+	}
+}
@@ -4,56 +4,568 @@
 package envcli
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
+	"os/signal"
+	"runtime"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"go.jetpack.io/envsec"
+	"go.jetpack.io/pkg/envvar"
 )
 
 type execCmdFlags struct {
 	configFlags
+	filterFlags
+	cacheFlags
+	concurrencyFlags
+	shell         bool
+	noInherit     bool
+	preferLocal   bool
+	expand        bool
+	dryRun        bool
+	showValues    bool
+	timeout       time.Duration
+	watch         bool
+	watchInterval time.Duration
+	envFrom       string
+	envFiles      []string
+	overrides     []string
+	logFile       string
+	keep          []string
+	printEnvFD    int
+	noExec        bool
+	quiet         bool
 }
 
+// timeoutExitCode is the status envsec exits with when --timeout expires. It
+// matches GNU timeout(1).
+const timeoutExitCode = 124
+
+// commandNotFoundExitCode is the status envsec exits with when the command
+// doesn't exist, the same as POSIX shells.
+const commandNotFoundExitCode = 127
+
+// defaultKeptVariables are the local variables --no-inherit keeps unless
+// --keep says otherwise: enough for most commands to find their tools, home
+// directory and terminal. SystemRoot and ComSpec are needed on Windows.
+var defaultKeptVariables = []string{
+	"PATH", "HOME", "USER", "TERM", "TMPDIR", "LANG", "SystemRoot", "ComSpec",
+}
+
+// stopGracePeriod is how long the command has to exit after being asked to
+// stop, by a timeout, a cancelled context or --watch, before it is killed.
+const stopGracePeriod = 10 * time.Second
+
 func ExecCmd() *cobra.Command {
 	flags := &execCmdFlags{}
 	command := &cobra.Command{
-		Use:   "exec <command>",
-		Short: "Execute a command with Jetpack-stored environment variables",
-		Long:  "Execute a specified command with remote environment variables being present for the duration of the command. If an environment variable exists both locally and in remote storage, the remotely stored one is prioritized.",
-		Args:  cobra.MinimumNArgs(1),
+		Use:     "exec [flags] [--] <command> [<arg>]...",
+		Aliases: []string{"run"},
+		Short:   "Execute a command with Jetpack-stored environment variables",
+		Long: "Execute a specified command with remote environment variables being present for the duration of the command. " +
+			"If an environment variable exists both locally and in remote storage, the remotely stored one is prioritized " +
+			"unless --prefer-local is set. " +
+			"The command and its arguments are passed through as-is; use --shell to run them through the shell instead. " +
+			"envsec's own flags must come before the command: everything from the command onwards, " +
+			"or after --, is passed to it untouched, so its flags are never mistaken for envsec's. " +
+			"With --no-inherit the command only sees the remote environment variables and the few local ones named by --keep. " +
+			"Variables are layered in this order, each overriding the ones before it: the local environment, " +
+			"the stored variables of --env-from, those of --environment (only if given explicitly when --env-from is set), " +
+			"--env-file files and --set values. " +
+			"--environment can be repeated, as in --environment base --environment us-east --environment billing, " +
+			"to merge the stored variables of several environments in that order, so that later ones win. " +
+			"Use --dry-run to see the resulting environment without running anything. " +
+			"A supervisor that runs the command itself can instead read the environment from --print-env-fd, " +
+			"with --no-exec so that envsec only resolves it. " +
+			"With --watch the command is restarted, after being sent SIGTERM, whenever the stored variables change. " +
+			"Use --quiet to keep envsec's own messages out of the command's output, so that only errors are reported.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if flags.noExec && flags.printEnvFD < 0 {
+				return errors.New("--no-exec requires --print-env-fd")
+			}
+			if flags.dryRun || flags.noExec {
+				return nil
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cmdCfg, err := flags.genConfig(cmd)
 			if err != nil {
 				return err
 			}
-			commandString := strings.Join(args, " ")
-			commandToRun := exec.Command("/bin/sh", "-c", commandString)
-
 			envID := envsec.EnvID{
 				OrgID:     cmdCfg.EnvID.OrgID,
 				ProjectID: cmdCfg.EnvID.ProjectID,
 				EnvName:   cmdCfg.EnvID.EnvName,
 			}
+			uncached := flags.concurrencyFlags.wrap(cmdCfg.Store)
+			store, err := flags.cacheFlags.wrap(uncached)
+			if err != nil {
+				return err
+			}
+			envIDs := []envsec.EnvID{}
+			if flags.envFrom != "" {
+				baseID := envID
+				baseID.EnvName = flags.envFrom
+				envIDs = append(envIDs, baseID)
+			}
+			if flags.envFrom == "" || cmd.Flags().Changed(environmentFlagName) {
+				envNames := flags.configFlags.envNames
+				if len(envNames) == 0 {
+					envNames = []string{envID.EnvName}
+				}
+				for _, envName := range envNames {
+					layerID := envID
+					layerID.EnvName = envName
+					envIDs = append(envIDs, layerID)
+				}
+			}
 			// Get list of stored env variables
-			envVars, err := cmdCfg.Store.List(cmd.Context(), envID)
+			loadFrom := func(store envsec.Store) func() ([]envsec.EnvVar, error) {
+				return func() ([]envsec.EnvVar, error) {
+					envVars, err := listMerged(cmd.Context(), store, envIDs)
+					if err != nil {
+						return nil, err
+					}
+					return flags.filter(envVars)
+				}
+			}
+			envVars, err := loadFrom(store)()
 			if err != nil {
-				return errors.WithStack(err)
+				return explainStoreError(err, envID)
+			}
+			if flags.dryRun {
+				env, err := flags.environment(envVars)
+				if err != nil {
+					return err
+				}
+				return printEnvironment(cmd.OutOrStdout(), env, flags.showValues)
+			}
+			if flags.printEnvFD >= 0 {
+				env, err := flags.environment(envVars)
+				if err != nil {
+					return err
+				}
+				if err := writeEnvironmentToFD(cmd, flags.printEnvFD, env); err != nil {
+					return err
+				}
+			}
+			if flags.noExec {
+				return nil
 			}
-			// Attach stored env variables to the command environment
-			commandToRun.Env = os.Environ()
-			for _, envVar := range envVars {
-				commandToRun.Env = append(commandToRun.Env, fmt.Sprintf("%s=%s", envVar.Name, envVar.Value))
+
+			if flags.logFile != "" {
+				// Nothing is buffered in between, so closing the file once
+				// the command has exited, however it stopped, loses nothing.
+				logFile, err := os.OpenFile(flags.logFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				defer logFile.Close()
+				cmd.SetOut(io.MultiWriter(cmd.OutOrStdout(), logFile))
+				cmd.SetErr(io.MultiWriter(cmd.ErrOrStderr(), logFile))
+			}
+
+			ctx := cmd.Context()
+			if flags.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, flags.timeout)
+				defer cancel()
+			}
+
+			if flags.watch {
+				// Poll past --cache-ttl, which would hide changes until the
+				// cached copy expires.
+				err = flags.runWatching(ctx, cmd, args, envVars, loadFrom(uncached))
+			} else {
+				err = flags.run(ctx, cmd, args, envVars)
 			}
-			commandToRun.Stdin = cmd.InOrStdin()
-			commandToRun.Stdout = cmd.OutOrStdout()
-			commandToRun.Stderr = cmd.ErrOrStderr()
-			return commandToRun.Run()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				fmt.Fprintf(cmd.ErrOrStderr(), "envsec: command timed out after %s\n", flags.timeout)
+				return exitStatus(timeoutExitCode)
+			}
+			if errors.Is(ctx.Err(), context.Canceled) {
+				// Report the cancellation rather than how the command died.
+				return errors.WithStack(ctx.Err())
+			}
+			return err
 		},
 	}
+	command.Flags().BoolVar(
+		&flags.shell,
+		"shell",
+		false,
+		"Join the arguments and run them with the system shell (/bin/sh -c, or %ComSpec% /C on Windows)",
+	)
+	command.Flags().BoolVar(
+		&flags.noInherit,
+		"no-inherit",
+		false,
+		"Don't pass the local environment to the command, only remote variables and those named by --keep",
+	)
+	command.Flags().StringSliceVar(
+		&flags.keep,
+		"keep",
+		defaultKeptVariables,
+		"Local variables to pass to the command even with --no-inherit. Use --keep= to keep none",
+	)
+	command.Flags().BoolVar(
+		&flags.preferLocal,
+		"prefer-local",
+		false,
+		"Keep local variables instead of overriding them with remote ones of the same name",
+	)
+	command.Flags().BoolVar(&flags.expand, "expand", false, expandFlagUsage)
+	command.Flags().BoolVar(
+		&flags.dryRun,
+		"dry-run",
+		false,
+		"Print the environment the command would get, and where each variable comes from, without running it",
+	)
+	command.Flags().BoolVar(
+		&flags.showValues,
+		"show-values",
+		false,
+		"Show variable values in --dry-run output instead of masking them",
+	)
+	command.Flags().DurationVar(
+		&flags.timeout,
+		"timeout",
+		0,
+		"Stop the command if it is still running after this long, such as 30s or 5m. "+
+			"It and the processes it started are sent SIGTERM, then killed 10s later if still running. "+
+			"When stdin is a terminal, the command is given the terminal's foreground while it runs, "+
+			"so Ctrl-C still reaches it but Ctrl-Z can't suspend it",
+	)
+	command.Flags().BoolVar(
+		&flags.watch,
+		"watch",
+		false,
+		"Restart the command whenever the stored variables change",
+	)
+	command.Flags().DurationVar(
+		&flags.watchInterval,
+		"watch-interval",
+		10*time.Second,
+		"How often --watch checks for changed variables",
+	)
+	command.Flags().StringVar(
+		&flags.envFrom,
+		"env-from",
+		"",
+		"Read the stored variables from this environment instead, such as prod. "+
+			"An explicitly given --environment is layered over it",
+	)
+	command.Flags().StringArrayVar(
+		&flags.envFiles,
+		"env-file",
+		nil,
+		"Also load variables from this .env file, overriding inherited and remote ones. "+
+			"Can be repeated; later files win. Add a ? suffix (.env.local?) to skip the file if it doesn't exist",
+	)
+	command.Flags().IntVar(
+		&flags.printEnvFD,
+		"print-env-fd",
+		-1,
+		"Write the resolved environment to this open file descriptor as NAME=VALUE entries, each ending in a NUL byte",
+	)
+	command.Flags().BoolVar(
+		&flags.noExec,
+		"no-exec",
+		false,
+		"Exit after writing the environment to --print-env-fd instead of running a command",
+	)
+	command.Flags().StringArrayVar(
+		&flags.overrides,
+		"set",
+		nil,
+		"Set a variable for the command, as NAME=VALUE, overriding all others. Can be repeated",
+	)
+	command.Flags().StringVar(
+		&flags.logFile,
+		"log-file",
+		"",
+		"Also write the command's stdout and stderr to this file, replacing its contents",
+	)
+	command.Flags().BoolVarP(
+		&flags.quiet,
+		"quiet",
+		"q",
+		false,
+		"Don't print envsec's own informational messages, such as --watch restarts. Errors are still printed",
+	)
 	flags.configFlags.register(command)
+	flags.filterFlags.register(command)
+	flags.cacheFlags.register(command)
+	flags.concurrencyFlags.register(command)
+	// There is nothing to watch for changes without the store.
+	command.MarkFlagsMutuallyExclusive("watch", "offline")
+	// Stop parsing flags at the first argument so that flags meant for the
+	// command, as in `envsec exec mytool --verbose`, are left for it.
+	command.Flags().SetInterspersed(false)
 	return command
 }
+
+// environment resolves envVars into the environment the command runs with.
+func (f *execCmdFlags) environment(envVars []envsec.EnvVar) (*envsec.Environment, error) {
+	env := envsec.Resolve(envVars, envsec.ResolveOptions{
+		NoInherit:   f.noInherit,
+		Keep:        f.keep,
+		PreferLocal: f.preferLocal,
+	})
+	if err := f.applyEnvFiles(env); err != nil {
+		return nil, err
+	}
+	if err := f.applyOverrides(env); err != nil {
+		return nil, err
+	}
+	if f.expand {
+		if err := expandRemote(env); err != nil {
+			return nil, err
+		}
+	}
+	return env, env.Validate()
+}
+
+// logOutput is where envsec's informational messages about the command go:
+// stderr, or nowhere with --quiet. Errors are always written to stderr.
+func (f *execCmdFlags) logOutput(cmd *cobra.Command) io.Writer {
+	if f.quiet {
+		return io.Discard
+	}
+	return cmd.ErrOrStderr()
+}
+
+// run runs the command once with envVars and waits for it to exit.
+func (f *execCmdFlags) run(
+	ctx context.Context,
+	cmd *cobra.Command,
+	args []string,
+	envVars []envsec.EnvVar,
+) error {
+	commandToRun, stopForwarding, err := f.start(ctx, cmd, args, envVars)
+	if err != nil {
+		return err
+	}
+	defer stopForwarding()
+	return childExitStatus(commandToRun.Wait())
+}
+
+// start launches the command with envVars and relays envsec's signals to it
+// until the returned function is called, once the command has exited. If ctx
+// ended the command, that function also kills whatever processes it left
+// behind.
+func (f *execCmdFlags) start(
+	ctx context.Context,
+	cmd *cobra.Command,
+	args []string,
+	envVars []envsec.EnvVar,
+) (*exec.Cmd, func(), error) {
+	env, err := f.environment(envVars)
+	if err != nil {
+		return nil, nil, err
+	}
+	commandToRun := f.command(ctx, args)
+	// When ctx is done, ask the command to stop and only kill it if it is
+	// still running after the grace period.
+	commandToRun.Cancel = func() error { return terminate(commandToRun.Process) }
+	commandToRun.WaitDelay = stopGracePeriod
+	// Attach stored env variables to the command environment
+	commandToRun.Env = env.Environ()
+	commandToRun.Stdin = cmd.InOrStdin()
+	commandToRun.Stdout = cmd.OutOrStdout()
+	commandToRun.Stderr = cmd.ErrOrStderr()
+	// Run the command in a process group of its own, so that stopping it
+	// also stops the processes it started. A command reading from a terminal
+	// only gets one when envsec may have to stop it, because the group must
+	// then become the terminal's foreground group, which costs Ctrl-Z.
+	// Otherwise it stays in envsec's group and the terminal's Ctrl-C reaches
+	// everything it started.
+	restoreTerminal := func() {}
+	ownGroup := true
+	if !isTerminal(commandToRun.Stdin) {
+		setProcessGroup(commandToRun)
+	} else if f.timeout > 0 || f.watch {
+		restoreTerminal = setForegroundProcessGroup(commandToRun)
+	} else {
+		ownGroup = false
+	}
+	if err := commandToRun.Start(); err != nil {
+		restoreTerminal()
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "envsec: command not found: %s\n", args[0])
+			return nil, nil, exitStatus(commandNotFoundExitCode)
+		}
+		return nil, nil, errors.WithStack(err)
+	}
+	stopForwarding := forwardSignals(commandToRun.Process, ownGroup)
+	return commandToRun, func() {
+		stopForwarding()
+		restoreTerminal()
+		if ctx.Err() != nil {
+			_ = kill(commandToRun.Process)
+		}
+	}, nil
+}
+
+// command builds the process to run. By default args[0] is looked up in PATH
+// and the remaining args are passed through unchanged. With --shell the args
+// are joined and handed to the shell, which was the original behavior.
+func (f *execCmdFlags) command(ctx context.Context, args []string) *exec.Cmd {
+	if f.shell {
+		shell, flag := systemShell()
+		return exec.CommandContext(ctx, shell, flag, strings.Join(args, " "))
+	}
+	return exec.CommandContext(ctx, args[0], args[1:]...)
+}
+
+// systemShell returns the shell used by --shell and the flag that makes it
+// run a command string.
+func systemShell() (string, string) {
+	if runtime.GOOS == "windows" {
+		return envvar.Get("ComSpec", "cmd.exe"), "/C"
+	}
+	return "/bin/sh", "-c"
+}
+
+// printEnvironment writes one line per variable with its source, sorted by
+// name. Values are masked unless showValues is set.
+func printEnvironment(w io.Writer, env *envsec.Environment, showValues bool) error {
+	entries := env.Entries()
+	slices.SortFunc(entries, func(a, b envsec.EnvEntry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, entry := range entries {
+		value := entry.Value
+		if !showValues {
+			value = maskValue(value)
+		}
+		if _, err := fmt.Fprintf(w, "%-6s  %s=%s\n", entry.Source, entry.Name, value); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// writeEnvironmentToFD writes env to the file descriptor fd in the format of
+// /proc/<pid>/environ: NAME=VALUE entries, each ending in a NUL byte, which
+// can't be part of a name or value. Descriptors 0 to 2 are cmd's stdin, stdout
+// and stderr. Any other descriptor is closed afterwards, so a reader sees the
+// end of it and the command doesn't inherit it.
+func writeEnvironmentToFD(cmd *cobra.Command, fd int, env *envsec.Environment) error {
+	var w io.Writer
+	switch fd {
+	case 0:
+		stdin, ok := cmd.InOrStdin().(io.Writer)
+		if !ok {
+			return errors.New("can't write the environment to stdin")
+		}
+		w = stdin
+	case 1:
+		w = cmd.OutOrStdout()
+	case 2:
+		w = cmd.ErrOrStderr()
+	default:
+		f := os.NewFile(uintptr(fd), fmt.Sprintf("fd %d", fd))
+		defer f.Close()
+		w = f
+	}
+	var buf bytes.Buffer
+	for _, entry := range env.Environ() {
+		buf.WriteString(entry)
+		buf.WriteByte(0)
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return errors.Wrapf(err, "failed to write the environment to file descriptor %d", fd)
+	}
+	return nil
+}
+
+// forwardedSignals are relayed to the child's process group so that stopping
+// envsec also stops the command it is running and everything it started.
+var forwardedSignals = []os.Signal{
+	os.Interrupt,
+	syscall.SIGTERM,
+	syscall.SIGHUP,
+	syscall.SIGQUIT,
+}
+
+// terminalSignals are the forwardedSignals that the terminal sends for Ctrl-C
+// and Ctrl-\ to its whole foreground process group.
+var terminalSignals = []os.Signal{os.Interrupt, syscall.SIGQUIT}
+
+// forwardSignals relays forwardedSignals received by envsec to proc until the
+// returned function is called. If proc is in envsec's own process group,
+// terminalSignals aren't relayed: whatever sent them to envsec, most likely
+// the terminal, sent them to proc too, and many programs take a second Ctrl-C
+// as a request to quit without cleaning up.
+func forwardSignals(proc *os.Process, ownGroup bool) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if !ownGroup && slices.Contains(terminalSignals, sig) {
+					continue
+				}
+				// The child may have exited in the meantime, in which case
+				// there is nothing left to signal.
+				_ = signalProcessGroup(proc, sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// exitStatus is returned by commands that have already reported what went
+// wrong and only need envsec to exit with a particular status.
+type exitStatus int
+
+func (s exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(s))
+}
+
+// childExitStatus turns the error of waiting for the command into the
+// exitStatus envsec should exit with when the command was started and then
+// failed, since the command has reported that failure itself. Other errors
+// are returned unchanged. A command killed by a signal maps to 128+N, the same
+// convention shells use.
+func childExitStatus(err error) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return exitStatus(128 + int(status.Signal()))
+	}
+	return exitStatus(exitErr.ExitCode())
+}
+
+// exitCode reports the status envsec should exit with when err is an
+// exitStatus. Any other error, including one from a process other than the
+// command run by `envsec exec`, is printed by Execute instead.
+func exitCode(err error) (int, bool) {
+	var status exitStatus
+	if errors.As(err, &status) {
+		return int(status), true
+	}
+	return 0, false
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.jetpack.io/envsec"
+	"go.jetpack.io/envsec/pkg/memstore"
+)
+
+func TestExecPrecedence(t *testing.T) {
+	dev := envsec.EnvID{ProjectID: "proj", OrgID: "org", EnvName: "dev"}
+	prod := envsec.EnvID{ProjectID: "proj", OrgID: "org", EnvName: "prod"}
+	layer := func(envName string) envsec.EnvID {
+		envID := dev
+		envID.EnvName = envName
+		return envID
+	}
+	store := memstore.NewWithVars(map[envsec.EnvID]map[string]string{
+		prod:             {"B": "prod", "C": "prod", "D": "prod", "E": "prod"},
+		dev:              {"C": "dev"},
+		layer("base"):    {"B": "base", "C": "base", "D": "base"},
+		layer("us-east"): {"C": "us-east", "D": "us-east"},
+		layer("billing"): {"D": "billing"},
+	})
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("D=file\nE=file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("A", "local")
+	t.Setenv("B", "local")
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			"base only",
+			[]string{"--env-from", "prod"},
+			[]string{"local   A=local", "remote  B=prod", "remote  C=prod", "remote  D=prod", "remote  E=prod"},
+		},
+		{
+			"all layers",
+			[]string{"--env-from", "prod", "--environment", "dev", "--env-file", envFile, "--set", "E=flag"},
+			[]string{"local   A=local", "remote  B=prod", "remote  C=dev", "file    D=file", "flag    E=flag"},
+		},
+		{
+			"repeated environments",
+			[]string{"--environment", "base", "--environment", "us-east", "--environment", "billing"},
+			[]string{"local   A=local", "remote  B=base", "remote  C=us-east", "remote  D=billing"},
+		},
+		{
+			"repeated environments over --env-from",
+			[]string{"--env-from", "prod", "--environment", "us-east", "--environment", "billing"},
+			[]string{"local   A=local", "remote  B=prod", "remote  C=us-east", "remote  D=billing", "remote  E=prod"},
+		},
+	}
+
+	names := []string{"A", "B", "C", "D", "E"}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := append([]string{"--dry-run", "--show-values"}, test.args...)
+			out, _, err := runWithStore(t, store, dev, ExecCmd(), args...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			result := []string{}
+			for _, line := range strings.Split(out, "\n") {
+				fields := strings.Fields(line)
+				if len(fields) != 2 {
+					continue
+				}
+				if name, _, _ := strings.Cut(fields[1], "="); slices.Contains(names, name) {
+					result = append(result, line)
+				}
+			}
+			if strings.Join(result, "\n") != strings.Join(test.expected, "\n") {
+				t.Errorf("Expected:\n%s\nbut got:\n%s", strings.Join(test.expected, "\n"), strings.Join(result, "\n"))
+			}
+		})
+	}
+}
+
+func TestExecPrintEnvFD(t *testing.T) {
+	envID := envsec.EnvID{ProjectID: "proj", OrgID: "org", EnvName: "dev"}
+	store := memstore.NewWithVars(map[envsec.EnvID]map[string]string{envID: {"A": "1", "B": "two\nlines"}})
+
+	tests := []struct {
+		name   string
+		args   []string
+		out    string
+		errOut string
+		err    bool
+	}{
+		{"stdout", []string{"--print-env-fd", "1", "--no-exec"}, "A=1\x00B=two\nlines\x00", "", false},
+		{"stderr", []string{"--print-env-fd", "2", "--no-exec"}, "", "A=1\x00B=two\nlines\x00", false},
+		{"no fd", []string{"--no-exec"}, "", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := append([]string{"--no-inherit", "--keep="}, test.args...)
+			out, errOut, err := runWithStore(t, store, envID, ExecCmd(), args...)
+			if (err != nil) != test.err {
+				t.Fatalf("Expected an error: %v, but got %v", test.err, err)
+			}
+			if out != test.out {
+				t.Errorf("Expected %q, but got %q", test.out, out)
+			}
+			if errOut != test.errOut {
+				t.Errorf("Expected %q, but got %q", test.errOut, errOut)
+			}
+		})
+	}
+}
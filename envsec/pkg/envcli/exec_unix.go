@@ -0,0 +1,66 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+//go:build !windows
+
+package envcli
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// setProcessGroup makes the command the leader of a new process group, which
+// the processes it starts join too, so that signals can reach all of them.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// setForegroundProcessGroup is setProcessGroup for a command whose stdin is a
+// terminal. The group is also made the terminal's foreground group, so that
+// the command can keep reading from it and gets Ctrl-C. Ctrl-Z is ignored,
+// because envsec, left in the background, couldn't hand a stopped command
+// back to the shell. The returned function gives the terminal back to envsec
+// once the command has exited.
+func setForegroundProcessGroup(cmd *exec.Cmd) (restore func()) {
+	tty := cmd.Stdin.(*os.File)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Foreground: true, Ctty: int(tty.Fd())}
+	// The command inherits the ignored signal.
+	signal.Ignore(syscall.SIGTSTP)
+	return func() {
+		// A background process that changes the foreground group is sent
+		// SIGTTOU, which would stop envsec.
+		signal.Ignore(syscall.SIGTTOU)
+		_ = unix.IoctlSetPointerInt(int(tty.Fd()), unix.TIOCSPGRP, unix.Getpgrp())
+		signal.Reset(syscall.SIGTTOU, syscall.SIGTSTP)
+	}
+}
+
+// signalProcessGroup sends sig to the process group led by proc, or only to
+// proc if it doesn't lead one.
+func signalProcessGroup(proc *os.Process, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return proc.Signal(sig)
+	}
+	err := syscall.Kill(-proc.Pid, s)
+	if errors.Is(err, syscall.ESRCH) {
+		return proc.Signal(sig)
+	}
+	return errors.WithStack(err)
+}
+
+// terminate asks proc and the processes it started to exit.
+func terminate(proc *os.Process) error {
+	return signalProcessGroup(proc, syscall.SIGTERM)
+}
+
+// kill stops proc and the processes it started right away.
+func kill(proc *os.Process) error {
+	return signalProcessGroup(proc, syscall.SIGKILL)
+}
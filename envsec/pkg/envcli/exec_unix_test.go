@@ -0,0 +1,162 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+//go:build !windows
+
+package envcli
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.jetpack.io/envsec"
+	"go.jetpack.io/envsec/pkg/memstore"
+)
+
+func TestExecStopsProcessesStartedByTheCommand(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	// The shell starts a child that ignores SIGTERM and waits for it, so
+	// only signalling the whole process group can stop both. stdin isn't a
+	// terminal here; when it is, start makes the group the terminal's
+	// foreground group instead (setForegroundProcessGroup), which needs a
+	// real terminal to test.
+	script := `(trap "" TERM; exec sleep 60 >/dev/null 2>&1) & echo $! > ` + pidFile + `; wait`
+
+	cmd := &cobra.Command{}
+	cmd.SetIn(strings.NewReader(""))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	flags := &execCmdFlags{}
+	if err := flags.run(ctx, cmd, []string{"/bin/sh", "-c", script}, nil); err == nil {
+		t.Fatal("Expected the command to be stopped by the timeout")
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for processRunning(pid) {
+		if time.Now().After(deadline) {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("Expected process %d started by the command to be stopped", pid)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// processRunning reports whether pid is running. A killed process whose
+// parent hasn't reaped it yet is a zombie, which doesn't count.
+func processRunning(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	out, err := exec.Command("ps", "-o", "stat=", "-p", strconv.Itoa(pid)).Output()
+	return err == nil && !strings.HasPrefix(strings.TrimSpace(string(out)), "Z")
+}
+
+func TestExecPrintEnvFDPipe(t *testing.T) {
+	envID := envsec.EnvID{ProjectID: "proj", OrgID: "org", EnvName: "dev"}
+	store := memstore.NewWithVars(map[envsec.EnvID]map[string]string{envID: {"A": "1", "B": "two\nlines"}})
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	// envsec closes the descriptor it writes to, so give it a copy of w's.
+	fd, err := syscall.Dup(int(w.Fd()))
+	w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"--no-inherit", "--keep=", "--print-env-fd", strconv.Itoa(fd), "--no-exec"}
+	if _, _, err := runWithStore(t, store, envID, ExecCmd(), args...); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The read only ends once envsec has closed the descriptor.
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "A=1\x00B=two\nlines\x00"; string(data) != expected {
+		t.Errorf("Expected %q, but got %q", expected, string(data))
+	}
+}
+
+func TestForwardSignalsSkipsTerminalSignalsInSharedGroup(t *testing.T) {
+	tests := []struct {
+		name     string
+		ownGroup bool
+		expected string
+	}{
+		{"own group", true, "INT\n"},
+		{"shared group", false, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out")
+			ready := out + ".ready"
+			script := `trap 'echo INT >> ` + out + `' INT; touch ` + ready + `; while :; do sleep 0.05; done`
+			// The child gets a group of its own in both cases, so that the
+			// signal envsec sends itself below only reaches it if relayed.
+			child := exec.Command("/bin/sh", "-c", script)
+			setProcessGroup(child)
+			if err := child.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = kill(child.Process)
+				_ = child.Wait()
+			}()
+			for _, err := os.Stat(ready); err != nil; _, err = os.Stat(ready) {
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			stop := forwardSignals(child.Process, test.ownGroup)
+			if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(500 * time.Millisecond)
+			stop()
+
+			data, _ := os.ReadFile(out)
+			if string(data) != test.expected {
+				t.Errorf("Expected %q, but got %q", test.expected, string(data))
+			}
+		})
+	}
+}
+
+func TestExecExitStatus(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.SetIn(strings.NewReader(""))
+	flags := &execCmdFlags{}
+	err := flags.run(context.Background(), cmd, []string{"/bin/sh", "-c", "exit 3"}, nil)
+	if code, ok := exitCode(err); !ok || code != 3 {
+		t.Errorf("Expected the command's exit status 3, but got %v", err)
+	}
+
+	// A process other than the command failing is an error to print.
+	otherErr := exec.Command("/bin/sh", "-c", "exit 3").Run()
+	if _, ok := exitCode(errors.Wrap(otherErr, "helper failed")); ok {
+		t.Errorf("Expected %v not to be taken for the command's exit status", otherErr)
+	}
+}
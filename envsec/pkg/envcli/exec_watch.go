@@ -0,0 +1,117 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.jetpack.io/envsec"
+)
+
+// runWatching runs the command like run does, but checks the store every
+// --watch-interval and restarts the command when the variables change. It
+// returns when the command exits on its own.
+func (f *execCmdFlags) runWatching(
+	ctx context.Context,
+	cmd *cobra.Command,
+	args []string,
+	envVars []envsec.EnvVar,
+	load func() ([]envsec.EnvVar, error),
+) error {
+	for {
+		commandToRun, stopForwarding, err := f.start(ctx, cmd, args, envVars)
+		if err != nil {
+			return err
+		}
+		done := make(chan error, 1)
+		go func() { done <- commandToRun.Wait() }()
+
+		latest, exited, err := waitForChange(cmd, f.logOutput(cmd), envVars, load, f.watchInterval, done)
+		if exited {
+			stopForwarding()
+			return childExitStatus(err)
+		}
+		stopGracefully(commandToRun.Process, done)
+		stopForwarding()
+		envVars = latest
+	}
+}
+
+// waitForChange polls load until the variables differ from envVars and
+// returns the new ones, reporting restarts to log. If the command exits
+// first, exited is true and err is the result of waiting for it.
+func waitForChange(
+	cmd *cobra.Command,
+	log io.Writer,
+	envVars []envsec.EnvVar,
+	load func() ([]envsec.EnvVar, error),
+	interval time.Duration,
+	done <-chan error,
+) (latest []envsec.EnvVar, exited bool, err error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			return nil, true, err
+		case <-ticker.C:
+			latest, err := load()
+			if err != nil {
+				// Keep the command running through transient failures.
+				fmt.Fprintf(cmd.ErrOrStderr(), "envsec: failed to check for changes: %v\n", err)
+				continue
+			}
+			if changed := changedNames(envVars, latest); len(changed) > 0 {
+				fmt.Fprintf(
+					log,
+					"envsec: restarting command, changed: %s\n",
+					strings.Join(changed, ", "),
+				)
+				return latest, false, nil
+			}
+		}
+	}
+}
+
+// stopGracefully asks proc to exit and kills it if it hasn't within
+// stopGracePeriod. done receives the result of waiting for proc. Processes it
+// started that are still running afterwards are killed, so they can't get in
+// the way of the restarted command.
+func stopGracefully(proc *os.Process, done <-chan error) {
+	_ = terminate(proc)
+	select {
+	case <-done:
+	case <-time.After(stopGracePeriod):
+		_ = kill(proc)
+		<-done
+	}
+	_ = kill(proc)
+}
+
+// changedNames returns the sorted names of variables that were added, removed
+// or changed between before and after.
+func changedNames(before, after []envsec.EnvVar) []string {
+	beforeMap := envVarMap(before)
+	afterMap := envVarMap(after)
+	changed := []string{}
+	for name, value := range beforeMap {
+		if newValue, ok := afterMap[name]; !ok || newValue != value {
+			changed = append(changed, name)
+		}
+	}
+	for name := range afterMap {
+		if _, ok := beforeMap[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
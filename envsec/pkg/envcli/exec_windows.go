@@ -0,0 +1,31 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup does nothing on Windows, which has no process groups that
+// signals can be sent to.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func setForegroundProcessGroup(cmd *exec.Cmd) (restore func()) {
+	return func() {}
+}
+
+func signalProcessGroup(proc *os.Process, sig os.Signal) error {
+	return proc.Signal(sig)
+}
+
+// terminate stops proc. Windows can't deliver SIGTERM, so the process is
+// killed right away.
+func terminate(proc *os.Process) error {
+	return proc.Kill()
+}
+
+func kill(proc *os.Process) error {
+	return proc.Kill()
+}
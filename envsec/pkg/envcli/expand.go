@@ -0,0 +1,119 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.jetpack.io/envsec"
+)
+
+const expandFlagUsage = "Expand ${NAME} references in values using the other variables. " +
+	"Write $$ for a literal $"
+
+// expandReferences replaces ${NAME} references in the values of vars. Names
+// defined in vars are expanded recursively; any other name is resolved with
+// lookup, if not nil, and its value is used as is. "$$" stands for a literal
+// "$", and a "$" followed by anything else is left alone.
+func expandReferences(
+	vars map[string]string,
+	lookup func(name string) (string, bool),
+) (map[string]string, error) {
+	x := &expander{raw: vars, lookup: lookup, expanded: map[string]string{}}
+	result := map[string]string{}
+	for name := range vars {
+		value, err := x.expand(name)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = value
+	}
+	return result, nil
+}
+
+// expandEnvVars is expandReferences for a list of variables that can only
+// refer to each other.
+func expandEnvVars(envVars []envsec.EnvVar) ([]envsec.EnvVar, error) {
+	expanded, err := expandReferences(envVarMap(envVars), nil)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]envsec.EnvVar, 0, len(envVars))
+	for _, envVar := range envVars {
+		expandedVar := envsec.EnvVar{Name: envVar.Name, Value: expanded[envVar.Name]}
+		// A plain value that refers to other variables may now contain a
+		// secret.
+		if expandedVar.Value == envVar.Value {
+			expandedVar.Sensitivity = envVar.Sensitivity
+		}
+		result = append(result, expandedVar)
+	}
+	return result, nil
+}
+
+type expander struct {
+	raw      map[string]string
+	lookup   func(name string) (string, bool)
+	expanded map[string]string
+	// names being expanded, outermost first, to detect cycles
+	visiting []string
+}
+
+func (x *expander) expand(name string) (string, error) {
+	if value, ok := x.expanded[name]; ok {
+		return value, nil
+	}
+	if i := slices.Index(x.visiting, name); i >= 0 {
+		cycle := append(slices.Clone(x.visiting[i:]), name)
+		return "", errors.Errorf("variable references form a cycle: %s", strings.Join(cycle, " -> "))
+	}
+	x.visiting = append(x.visiting, name)
+	defer func() { x.visiting = x.visiting[:len(x.visiting)-1] }()
+
+	raw := x.raw[name]
+	var b strings.Builder
+	for i := 0; i < len(raw); {
+		if raw[i] != '$' || i+1 == len(raw) {
+			b.WriteByte(raw[i])
+			i++
+			continue
+		}
+		switch raw[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i += 2
+		case '{':
+			end := strings.IndexByte(raw[i+2:], '}')
+			if end < 0 {
+				return "", errors.Errorf("%s has a ${ without a closing }", name)
+			}
+			value, err := x.resolve(name, raw[i+2:i+2+end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i += end + 3
+		default:
+			b.WriteByte('$')
+			i++
+		}
+	}
+
+	x.expanded[name] = b.String()
+	return x.expanded[name], nil
+}
+
+func (x *expander) resolve(from, ref string) (string, error) {
+	if _, ok := x.raw[ref]; ok {
+		return x.expand(ref)
+	}
+	if x.lookup != nil {
+		if value, ok := x.lookup(ref); ok {
+			return value, nil
+		}
+	}
+	return "", errors.Errorf("%s references undefined variable %q", from, ref)
+}
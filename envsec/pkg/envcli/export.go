@@ -0,0 +1,307 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"go.jetpack.io/envsec"
+)
+
+type exportCmdFlags struct {
+	configFlags
+	filterFlags
+	cacheFlags
+	concurrencyFlags
+	format     string
+	shell      bool
+	fish       bool
+	expand     bool
+	showValues bool
+	maskAll    bool
+}
+
+func ExportCmd() *cobra.Command {
+	flags := &exportCmdFlags{}
+	command := &cobra.Command{
+		Use:   "export",
+		Short: "Print environment variables in dotenv, JSON or shell format",
+		Long: "Print the stored environment variables to stdout so they can be piped into other tools. " +
+			"The default dotenv format prints one NAME=\"VALUE\" per line. " +
+			"The json format prints a single object mapping names to their exact values. " +
+			"The jsonl format prints one {\"name\":...,\"value\":...} object per line, " +
+			"streaming them in batches as they are fetched instead of waiting for all of them, " +
+			"unless --expand is set. " +
+			"The shell and fish formats print commands that load the variables into the current shell, " +
+			"for example: eval \"$(envsec export --shell --show-values)\" or envsec export --fish --show-values | source. " +
+			"The values of secrets, and with --mask-all of plain configuration too, are masked " +
+			"unless --show-values is set, including when the output is piped or redirected.",
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if flags.shell {
+				flags.format = "shell"
+			} else if flags.fish {
+				flags.format = "fish"
+			}
+			switch flags.format {
+			case "dotenv", "json", "jsonl", "shell", "fish":
+				return nil
+			}
+			return errors.Wrapf(errUnsupportedFormat, "format: %s", flags.format)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmdCfg, err := flags.genConfig(cmd)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			store, err := flags.cacheFlags.wrap(flags.concurrencyFlags.wrap(cmdCfg.Store))
+			if err != nil {
+				return err
+			}
+			if flags.format == "jsonl" && !flags.expand {
+				return flags.streamJSONLines(cmd, store, cmdCfg.EnvID)
+			}
+			envVars, err := store.List(cmd.Context(), cmdCfg.EnvID)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			envVars, err = flags.filter(envVars)
+			if err != nil {
+				return err
+			}
+			if flags.expand {
+				envVars, err = expandEnvVars(envVars)
+				if err != nil {
+					return err
+				}
+			}
+			return writeExport(cmd.OutOrStdout(), flags.format, flags.mask(envVars))
+		},
+	}
+
+	command.Flags().StringVarP(
+		&flags.format, "format", "f", "dotenv", "Output format: dotenv, json, jsonl, shell or fish")
+	command.Flags().BoolVar(
+		&flags.shell, "shell", false, "Shorthand for --format shell (bash, zsh and other POSIX shells)")
+	command.Flags().BoolVar(
+		&flags.fish, "fish", false, "Shorthand for --format fish")
+	command.Flags().BoolVar(&flags.expand, "expand", false, expandFlagUsage)
+	command.Flags().BoolVar(
+		&flags.showValues, "show-values", false, "Print the values of secrets in full instead of masking them")
+	command.Flags().BoolVar(
+		&flags.maskAll, "mask-all", false, "Also mask values the store marks as plain configuration")
+	command.MarkFlagsMutuallyExclusive("format", "shell", "fish")
+	command.MarkFlagsMutuallyExclusive("show-values", "mask-all")
+	flags.configFlags.register(command)
+	flags.filterFlags.register(command)
+	flags.cacheFlags.register(command)
+	flags.concurrencyFlags.register(command)
+
+	return command
+}
+
+// mask masks the values of secrets in envVars, or of all of them with
+// --mask-all, unless --show-values is set.
+func (f *exportCmdFlags) mask(envVars []envsec.EnvVar) []envsec.EnvVar {
+	if !f.showValues {
+		return maskValues(envVars, f.maskAll)
+	}
+	return envVars
+}
+
+// streamBatchSize is how many variables streamJSONLines fetches at a time.
+const streamBatchSize = 100
+
+// streamJSONLines prints the variables in the jsonl format one batch at a
+// time, so that the first ones are printed before the last ones are fetched.
+// Stores that can't list names are listed in one go instead.
+func (f *exportCmdFlags) streamJSONLines(
+	cmd *cobra.Command,
+	store envsec.Store,
+	envID envsec.EnvID,
+) error {
+	ctx := cmd.Context()
+	names, err := envsec.ListNames(ctx, store, envID)
+	if errors.Is(err, envsec.ErrNamesUnsupported) {
+		envVars, err := store.List(ctx, envID)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return f.writeJSONLines(cmd, envVars)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	slices.Sort(names)
+	for _, batch := range lo.Chunk(names, streamBatchSize) {
+		envVars, err := store.GetAll(ctx, envID, batch)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := f.writeJSONLines(cmd, envVars); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *exportCmdFlags) writeJSONLines(cmd *cobra.Command, envVars []envsec.EnvVar) error {
+	envVars, err := f.filter(envVars)
+	if err != nil {
+		return err
+	}
+	return writeExport(cmd.OutOrStdout(), "jsonl", f.mask(envVars))
+}
+
+func writeExport(w io.Writer, format string, envVars []envsec.EnvVar) error {
+	if format == "jsonl" {
+		return writeJSONLines(w, envVars)
+	}
+	if format == "json" {
+		// encodeToJSON writes map keys in sorted order and leaves values
+		// unescaped beyond what JSON itself requires.
+		contents, err := encodeToJSON(envVarMap(envVars))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		_, err = w.Write(contents)
+		return errors.WithStack(err)
+	}
+
+	formatLine := dotenvLine
+	switch format {
+	case "shell":
+		formatLine = shellLine
+	case "fish":
+		formatLine = fishLine
+	}
+
+	envVars = slices.Clone(envVars)
+	slices.SortFunc(envVars, func(a, b envsec.EnvVar) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, envVar := range envVars {
+		line, err := formatLine(envVar)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// jsonLine is a variable as printed in the jsonl format.
+type jsonLine struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// writeJSONLines writes each variable as a JSON object on its own line, in
+// name order. Every line is written as soon as it is encoded.
+func writeJSONLines(w io.Writer, envVars []envsec.EnvVar) error {
+	envVars = slices.Clone(envVars)
+	slices.SortFunc(envVars, func(a, b envsec.EnvVar) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, envVar := range envVars {
+		if err := encoder.Encode(jsonLine{Name: envVar.Name, Value: envVar.Value}); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func envVarMap(envVars []envsec.EnvVar) map[string]string {
+	m := map[string]string{}
+	for _, envVar := range envVars {
+		m[envVar.Name] = envVar.Value
+	}
+	return m
+}
+
+// dotenvEscaper escapes the characters that are special inside a double
+// quoted dotenv value, matching what godotenv expects when reading it back.
+var dotenvEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"\n", `\n`,
+	"\r", `\r`,
+	`"`, `\"`,
+	"!", `\!`,
+	"$", `\$`,
+	"`", "\\`",
+)
+
+// dotenvLine formats a variable as NAME="VALUE". Unlike godotenv.Marshal it
+// always quotes, so values such as 007 are not rewritten as numbers.
+//
+// godotenv ends a quoted value at the first quote that doesn't follow a
+// backslash, then trims every quote from its end. So a value ending in a
+// backslash gets an extra closing quote, which is trimmed, and one ending in a
+// double quote is single quoted, which godotenv reads verbatim.
+func dotenvLine(envVar envsec.EnvVar) (string, error) {
+	value := envVar.Value
+	switch {
+	case strings.HasSuffix(value, `\`):
+		return fmt.Sprintf(`%s="%s""`, envVar.Name, dotenvEscaper.Replace(value)), nil
+	case strings.HasSuffix(value, `"`):
+		// Single quoted values can't contain single quotes, and godotenv
+		// turns \r\n into \n in them.
+		if strings.ContainsAny(value, "'\r") {
+			return "", errors.Errorf(
+				"the value of %s can't be written in the dotenv format, use --format json instead",
+				envVar.Name,
+			)
+		}
+		return fmt.Sprintf("%s='%s'", envVar.Name, value), nil
+	}
+	return fmt.Sprintf(`%s="%s"`, envVar.Name, dotenvEscaper.Replace(value)), nil
+}
+
+// shellLine formats a variable as an export statement for POSIX shells. The
+// value is single quoted, so the only character that needs escaping is the
+// single quote itself.
+func shellLine(envVar envsec.EnvVar) (string, error) {
+	if err := validateShellName(envVar.Name); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(
+		"export %s='%s'",
+		envVar.Name,
+		strings.ReplaceAll(envVar.Value, "'", `'\''`),
+	), nil
+}
+
+// validateShellName refuses names that aren't valid variable names, which
+// shellLine and fishLine can't quote: a name stored with set --force, or by
+// another tool, could otherwise run commands in the shell that evals them.
+func validateShellName(name string) error {
+	if err := validateName(name, false); err != nil {
+		return errors.Wrap(err, "the shell and fish formats only export valid names")
+	}
+	return nil
+}
+
+// fishEscaper escapes the characters that are special inside a single quoted
+// fish string.
+var fishEscaper = strings.NewReplacer(`\`, `\\`, "'", `\'`)
+
+// fishLine formats a variable as a fish set command.
+func fishLine(envVar envsec.EnvVar) (string, error) {
+	if err := validateShellName(envVar.Name); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("set -gx %s '%s'", envVar.Name, fishEscaper.Replace(envVar.Value)), nil
+}
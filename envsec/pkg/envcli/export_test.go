@@ -0,0 +1,145 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/joho/godotenv"
+	"go.jetpack.io/envsec"
+	"go.jetpack.io/envsec/pkg/memstore"
+)
+
+func TestExportJSONLines(t *testing.T) {
+	envID := envsec.EnvID{ProjectID: "proj", OrgID: "org", EnvName: "dev"}
+	vars := map[string]string{"SKIPPED": "x"}
+	for i := 0; i < streamBatchSize*2+1; i++ {
+		vars[fmt.Sprintf("VAR_%03d", i)] = fmt.Sprintf("<%d>\n", i)
+	}
+	store := memstore.NewWithVars(map[envsec.EnvID]map[string]string{envID: vars})
+	out, _, err := runWithStore(t, store, envID, ExportCmd(), "--format", "jsonl", "--only", "VAR_*", "--show-values")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != streamBatchSize*2+1 {
+		t.Fatalf("Expected %d lines, but got %d", streamBatchSize*2+1, len(lines))
+	}
+	if expected := `{"name":"VAR_000","value":"<0>\n"}`; lines[0] != expected {
+		t.Errorf("Expected %s, but got %s", expected, lines[0])
+	}
+	if expected := `{"name":"VAR_200","value":"<200>\n"}`; lines[200] != expected {
+		t.Errorf("Expected %s, but got %s", expected, lines[200])
+	}
+}
+
+func TestExportMasksSecrets(t *testing.T) {
+	envID := envsec.EnvID{ProjectID: "proj", OrgID: "org", EnvName: "dev"}
+	store := memstore.NewWithVars(map[envsec.EnvID]map[string]string{envID: {"SECRET": "correct-horse-battery"}})
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"masked by default", nil, "SECRET=\"co" + valueMask + "ry\"\n"},
+		{"show values", []string{"--show-values"}, "SECRET=\"correct-horse-battery\"\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, _, err := runWithStore(t, store, envID, ExportCmd(), test.args...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if out != test.expected {
+				t.Errorf("Expected %q, but got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	values := []string{
+		"plain",
+		"",
+		"007",
+		"multi\nline",
+		"crlf\r\n",
+		`end\`,
+		`\`,
+		`back\nslash`,
+		`say "hi"`,
+		`"quoted"`,
+		"it's",
+		"$HOME and ${HOME}",
+		"`date` and $(date)",
+		"!bang",
+		" spaced # not a comment ",
+	}
+	// Each format is read back the way its users would read it.
+	readers := map[string]func(t *testing.T, out string) string{
+		"dotenv": func(t *testing.T, out string) string {
+			envMap, err := godotenv.Unmarshal(out)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			return envMap["VAR"]
+		},
+		"shell": func(t *testing.T, out string) string {
+			return runShell(t, "sh", out+`printf %s "$VAR"`)
+		},
+		"fish": func(t *testing.T, out string) string {
+			return runShell(t, "fish", out+"printf %s $VAR")
+		},
+	}
+
+	for format, read := range readers {
+		for _, value := range values {
+			t.Run(fmt.Sprintf("%s %q", format, value), func(t *testing.T) {
+				var out bytes.Buffer
+				envVars := []envsec.EnvVar{{Name: "VAR", Value: value}}
+				if err := writeExport(&out, format, envVars); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if got := read(t, out.String()); got != value {
+					t.Errorf("Expected %q, but got %q from %q", value, got, out.String())
+				}
+			})
+		}
+	}
+}
+
+func TestExportShellRefusesInvalidNames(t *testing.T) {
+	envVars := []envsec.EnvVar{{Name: "A;curl x|sh", Value: "1"}}
+	for _, format := range []string{"shell", "fish"} {
+		var out bytes.Buffer
+		if err := writeExport(&out, format, envVars); err == nil {
+			t.Errorf("Expected an error for the %s format, but got %q", format, out.String())
+		}
+	}
+}
+
+func TestExportDotenvUnrepresentable(t *testing.T) {
+	envVars := []envsec.EnvVar{{Name: "VAR", Value: `it's "quoted"`}}
+	if err := writeExport(&bytes.Buffer{}, "dotenv", envVars); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func runShell(t *testing.T, shell string, script string) string {
+	if _, err := exec.LookPath(shell); err != nil {
+		t.Skipf("%s isn't installed", shell)
+	}
+	out, err := exec.Command(shell, "-c", script).Output()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return string(out)
+}
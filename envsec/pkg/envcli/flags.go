@@ -4,8 +4,13 @@
 package envcli
 
 import (
+	"context"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -13,6 +18,7 @@ import (
 	"go.jetpack.io/envsec/internal/build"
 	"go.jetpack.io/envsec/pkg/awsfed"
 	envsecLib "go.jetpack.io/envsec/pkg/envsec"
+	"go.jetpack.io/envsec/pkg/filestore"
 	"go.jetpack.io/pkg/auth/session"
 	"go.jetpack.io/pkg/envvar"
 	"go.jetpack.io/pkg/id"
@@ -21,9 +27,12 @@ import (
 
 // to be composed into xyzCmdFlags structs
 type configFlags struct {
-	projectID string
-	orgID     string
-	envName   string
+	projectID     string
+	orgID         string
+	envName       string
+	envNames      []string
+	retries       int
+	retryMaxDelay time.Duration
 }
 
 func (f *configFlags) register(cmd *cobra.Command) {
@@ -41,12 +50,75 @@ func (f *configFlags) register(cmd *cobra.Command) {
 		"Organization id to namespace secrets by",
 	)
 
-	cmd.PersistentFlags().StringVar(
-		&f.envName,
+	f.envName = "dev"
+	cmd.PersistentFlags().Var(
+		&envNameValue{name: &f.envName, all: &f.envNames},
 		"environment",
-		"dev",
 		"Environment name, such as dev or prod",
 	)
+
+	cmd.PersistentFlags().IntVar(
+		&f.retries,
+		"retries",
+		3,
+		"Number of times to retry requests that fail with network errors or throttling",
+	)
+
+	cmd.PersistentFlags().DurationVar(
+		&f.retryMaxDelay,
+		"retry-max-delay",
+		5*time.Second,
+		"Longest time to wait between retries",
+	)
+
+	_ = cmd.RegisterFlagCompletionFunc(environmentFlagName, f.completeEnvNames)
+}
+
+// envNameValue is the value of --environment. The flag can be given several
+// times: envName is the last value, which most commands use, and envNames
+// all of them in order, for commands that merge environments.
+type envNameValue struct {
+	name *string
+	all  *[]string
+}
+
+func (v *envNameValue) String() string {
+	if v.name == nil {
+		return ""
+	}
+	return *v.name
+}
+
+func (v *envNameValue) Set(value string) error {
+	*v.name = value
+	*v.all = append(*v.all, value)
+	return nil
+}
+
+func (v *envNameValue) Type() string {
+	return "string"
+}
+
+// defaultEnvNames are the environments commands act on when none is selected.
+var defaultEnvNames = []string{"dev", "prod", "preview"}
+
+// completeEnvNames completes --environment with the environments that exist in
+// the project, or with the default ones if the store can't list them.
+func (f *configFlags) completeEnvNames(
+	cmd *cobra.Command,
+	args []string,
+	toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	cmdCfg, err := f.genConfig(cmd)
+	if err == nil {
+		var envNames []string
+		envNames, err = envsec.ListEnvNames(cmd.Context(), cmdCfg.Store, cmdCfg.EnvID)
+		if err == nil {
+			return envNames, cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+	cobra.CompDebugln(err.Error(), false)
+	return defaultEnvNames, cobra.ShellCompDirectiveNoFileComp
 }
 
 func (f *configFlags) validateProjectID(orgID id.OrgID) (string, error) {
@@ -93,9 +165,20 @@ func (f *configFlags) genConfig(cmd *cobra.Command) (*CmdConfig, error) {
 
 	ctx := cmd.Context()
 	var tok *session.Token
-	var err error
+	envSelected, err := f.applyDefaults(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// --offline only reads from the local cache, so it neither logs in nor
+	// builds a store to fetch from; the cache is put in front of a nil store.
+	offline, _ := cmd.Flags().GetBool("offline")
 
-	if f.orgID == "" {
+	// Only an explicit --org-id, or a local file store, skips logging in. An
+	// org ID from the environment or .envsec.json still uses the user's
+	// session.
+	fileStorePath := os.Getenv("ENVSEC_FILE_STORE")
+	if !offline && !cmd.Flags().Changed("org-id") && fileStorePath == "" {
 		client, err := newAuthClient()
 		if err != nil {
 			return nil, err
@@ -111,34 +194,32 @@ func (f *configFlags) genConfig(cmd *cobra.Command) (*CmdConfig, error) {
 	}
 
 	var store envsec.Store
-	if envvar.Bool("ENVSEC_USE_AWS_STORE") {
-		// Temporary hack to enable the AWS store
-		ssmConfig, err := awsfed.GenSSMConfigFromToken(ctx, tok, true /*useCache*/)
-		if err != nil {
-			return nil, errors.WithStack(err)
-		}
-		store, err = envsec.NewStore(ctx, ssmConfig)
-		if err != nil {
-			return nil, errors.WithStack(err)
-		}
-	} else {
-		store, err = envsec.NewStore(ctx, envsec.NewJetpackAPIConfig(tok))
+	if !offline {
+		store, err = f.newStore(ctx, tok, fileStorePath)
 		if err != nil {
-			return nil, errors.WithStack(err)
+			return nil, err
 		}
 	}
 
-	if err != nil {
-		return nil, errors.WithStack(err)
-	}
-
 	if tok != nil && f.orgID == "" {
 		f.orgID = tok.IDClaims().OrgID
 	}
 
+	if f.orgID == "" && offline {
+		return nil, errors.New(
+			"--offline needs an organization ID, since it doesn't log in to take it from. " +
+				"Set one with --org-id, ENVSEC_ORG_ID or .envsec.json",
+		)
+	}
+	if f.orgID == "" && fileStorePath != "" {
+		return nil, errors.New(
+			"ENVSEC_FILE_STORE needs an organization ID, since there is no login to take it from. " +
+				"Set one with --org-id or ENVSEC_ORG_ID, such as org_00000000000000000000000000",
+		)
+	}
 	orgID, err := typeid.Parse[id.OrgID](f.orgID)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, errors.Wrapf(err, "invalid organization ID %q", f.orgID)
 	}
 
 	projectID, err := f.validateProjectID(orgID)
@@ -151,8 +232,8 @@ func (f *configFlags) genConfig(cmd *cobra.Command) (*CmdConfig, error) {
 		return nil, errors.WithStack(err)
 	}
 
-	envNames := []string{"dev", "prod", "preview"}
-	if cmd.Flags().Changed(environmentFlagName) {
+	envNames := defaultEnvNames
+	if envSelected {
 		envNames = []string{envid.EnvName}
 	}
 
@@ -163,6 +244,56 @@ func (f *configFlags) genConfig(cmd *cobra.Command) (*CmdConfig, error) {
 	}, nil
 }
 
+// newStore builds the store variables are read from and written to: the file
+// store at fileStorePath if it is set, and otherwise AWS or the Jetpack API.
+func (f *configFlags) newStore(
+	ctx context.Context,
+	tok *session.Token,
+	fileStorePath string,
+) (envsec.Store, error) {
+	var store envsec.Store
+	var err error
+	if fileStorePath != "" {
+		// For development: keep variables in an encrypted local file instead
+		// of a remote service, so that envsec works offline.
+		store, err = newFileStore(fileStorePath)
+		if err != nil {
+			return nil, err
+		}
+	} else if envvar.Bool("ENVSEC_USE_AWS_STORE") {
+		// Temporary hack to enable the AWS store
+		ssmConfig, err := awsfed.GenSSMConfigFromToken(ctx, tok, true /*useCache*/)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		store, err = envsec.NewStore(ctx, ssmConfig)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	} else {
+		store, err = envsec.NewStore(ctx, envsec.NewJetpackAPIConfig(tok))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return envsec.NewRetryStore(store, envsec.RetryOptions{
+		Retries:  f.retries,
+		MaxDelay: f.retryMaxDelay,
+	}), nil
+}
+
+// newFileStore opens the file store at path with the hex-encoded key in
+// ENVSEC_FILE_STORE_KEY.
+func newFileStore(path string) (envsec.Store, error) {
+	key, err := hex.DecodeString(os.Getenv("ENVSEC_FILE_STORE_KEY"))
+	if err != nil || len(key) == 0 {
+		return nil, errors.New(
+			"ENVSEC_FILE_STORE_KEY must be set to a hex-encoded AES key to use ENVSEC_FILE_STORE",
+		)
+	}
+	return filestore.New(path, key)
+}
+
 var bootstrappedConfig *CmdConfig
 
 // BootstrapConfig is used to set the config for all commands that use genConfig
@@ -170,3 +301,135 @@ var bootstrappedConfig *CmdConfig
 func BootstrapConfig(cmdConfig *CmdConfig) {
 	bootstrappedConfig = cmdConfig
 }
+
+// to be composed into xyzCmdFlags structs of commands that read variables
+type filterFlags struct {
+	only       []string
+	exclude    []string
+	ignoreCase bool
+}
+
+func (f *filterFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(
+		&f.only,
+		"only",
+		nil,
+		"Only include variables whose names match one of these glob patterns, such as DB_*",
+	)
+	cmd.Flags().StringSliceVar(
+		&f.exclude,
+		"exclude",
+		nil,
+		"Leave out variables whose names match one of these glob patterns. Takes precedence over --only",
+	)
+	cmd.Flags().BoolVar(
+		&f.ignoreCase,
+		"ignore-case",
+		false,
+		"Match --only and --exclude patterns case-insensitively",
+	)
+}
+
+// filter returns the variables selected by --only and --exclude.
+func (f *filterFlags) filter(envVars []envsec.EnvVar) ([]envsec.EnvVar, error) {
+	result := []envsec.EnvVar{}
+	for _, envVar := range envVars {
+		excluded, err := f.matchesAny(f.exclude, envVar.Name)
+		if err != nil {
+			return nil, err
+		}
+		if excluded {
+			continue
+		}
+		included := true
+		if len(f.only) > 0 {
+			included, err = f.matchesAny(f.only, envVar.Name)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if included {
+			result = append(result, envVar)
+		}
+	}
+	return result, nil
+}
+
+func (f *filterFlags) matchesAny(patterns []string, name string) (bool, error) {
+	if f.ignoreCase {
+		name = strings.ToLower(name)
+	}
+	for _, pattern := range patterns {
+		if f.ignoreCase {
+			pattern = strings.ToLower(pattern)
+		}
+		matched, err := path.Match(pattern, name)
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid pattern %q", pattern)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// to be composed into xyzCmdFlags structs of commands that read variables
+type cacheFlags struct {
+	cacheTTL time.Duration
+	offline  bool
+}
+
+func (f *cacheFlags) register(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(
+		&f.cacheTTL,
+		"cache-ttl",
+		0,
+		"Reuse variables fetched within this long (such as 10m) from an encrypted local cache",
+	)
+	cmd.Flags().BoolVar(
+		&f.offline,
+		"offline",
+		false,
+		"Only read variables from the local cache, without logging in. The environment must have been cached with --cache-ttl first",
+	)
+}
+
+// wrap puts the local cache in front of store when --cache-ttl or --offline
+// is set, and returns store unchanged otherwise.
+func (f *cacheFlags) wrap(store envsec.Store) (envsec.Store, error) {
+	if f.cacheTTL <= 0 && !f.offline {
+		return store, nil
+	}
+	cached, err := envsec.NewCachedStore(store, envsec.CacheOptions{
+		TTL:     f.cacheTTL,
+		Offline: f.offline,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return cached, nil
+}
+
+// to be composed into xyzCmdFlags structs of commands that read variables
+type concurrencyFlags struct {
+	concurrency int
+}
+
+func (f *concurrencyFlags) register(cmd *cobra.Command) {
+	cmd.Flags().IntVar(
+		&f.concurrency,
+		"concurrency",
+		1,
+		"Number of requests to fetch variables with in parallel, for stores that fetch them in batches",
+	)
+}
+
+// wrap parallelizes fetching variables from store when --concurrency is more
+// than 1, and returns store unchanged otherwise.
+func (f *concurrencyFlags) wrap(store envsec.Store) envsec.Store {
+	if f.concurrency <= 1 {
+		return store
+	}
+	return envsec.NewConcurrentStore(store, f.concurrency)
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.jetpack.io/envsec"
+)
+
+// notFoundExitCode is the status envsec get exits with when the variable
+// isn't set, so that scripts can tell it apart from other failures, which
+// exit with 1.
+const notFoundExitCode = 2
+
+type getCmdFlags struct {
+	configFlags
+}
+
+func GetCmd() *cobra.Command {
+	flags := &getCmdFlags{}
+	command := &cobra.Command{
+		Use:   "get <NAME>",
+		Short: "Print the value of a stored environment variable",
+		Long: "Print the value of a stored environment variable, and nothing else, so that it can be used " +
+			"in scripts as in VALUE=$(envsec get NAME). " +
+			fmt.Sprintf("Exits with status %d if the variable isn't set.", notFoundExitCode),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmdCfg, err := flags.genConfig(cmd)
+			if err != nil {
+				return err
+			}
+			name := args[0]
+			value, err := cmdCfg.Store.Get(cmd.Context(), cmdCfg.EnvID, name)
+			if errors.Is(err, envsec.ErrKeyNotFound) {
+				fmt.Fprintf(
+					cmd.ErrOrStderr(),
+					"envsec: variable %s is not set in environment: %s\n",
+					name,
+					strings.ToLower(cmdCfg.EnvID.EnvName),
+				)
+				return exitStatus(notFoundExitCode)
+			}
+			if err != nil {
+				return explainStoreError(errors.WithStack(err), cmdCfg.EnvID)
+			}
+			_, err = io.WriteString(cmd.OutOrStdout(), value)
+			return errors.WithStack(err)
+		},
+	}
+	flags.configFlags.register(command)
+	return command
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.jetpack.io/envsec"
+	"go.jetpack.io/envsec/internal/tux"
+)
+
+type historyCmdFlags struct {
+	configFlags
+	showValues bool
+}
+
+func HistoryCmd() *cobra.Command {
+	flags := &historyCmdFlags{}
+	command := &cobra.Command{
+		Use:   "history <NAME>",
+		Short: "Show the previous values of a stored environment variable",
+		Long: "Show every version of a stored environment variable, newest first, with when it was written " +
+			"and by whom. Values are masked unless --show-values is set. " +
+			"Only stores that keep previous versions, such as AWS Parameter Store, support this.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmdCfg, err := flags.genConfig(cmd)
+			if err != nil {
+				return err
+			}
+			name := args[0]
+			envName := strings.ToLower(cmdCfg.EnvID.EnvName)
+			versions, err := envsec.History(cmd.Context(), cmdCfg.Store, cmdCfg.EnvID, name)
+			if errors.Is(err, envsec.ErrVersionsUnsupported) {
+				return errors.New(
+					"history isn't available: the store envsec is using doesn't keep previous versions of variables",
+				)
+			}
+			if errors.Is(err, envsec.ErrKeyNotFound) {
+				return errors.Errorf("variable %s is not set in environment: %s", name, envName)
+			}
+			if err != nil {
+				return explainStoreError(errors.WithStack(err), cmdCfg.EnvID)
+			}
+
+			err = tux.WriteHeader(cmd.OutOrStdout(), "History of %s in environment: %s\n", name, envName)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			table := tablewriter.NewWriter(cmd.OutOrStdout())
+			table.SetHeader([]string{"Version", "Modified", "By", "Value"})
+			for _, version := range versions {
+				value := version.Value
+				if !flags.showValues {
+					value = maskValue(value)
+				}
+				table.Append([]string{
+					strconv.FormatInt(version.Number, 10),
+					version.ModifiedAt.Local().Format(time.RFC3339),
+					version.ModifiedBy,
+					value,
+				})
+			}
+			table.Render()
+			return nil
+		},
+	}
+	command.Flags().BoolVar(
+		&flags.showValues,
+		"show-values",
+		false,
+		"Show the values of the versions instead of masking them",
+	)
+	flags.configFlags.register(command)
+	return command
+}
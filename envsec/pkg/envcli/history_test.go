@@ -0,0 +1,83 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.jetpack.io/envsec"
+	"go.jetpack.io/envsec/pkg/memstore"
+)
+
+// versionedStore is a store with a fixed history for every variable.
+type versionedStore struct {
+	*memstore.Store
+}
+
+func (s *versionedStore) History(ctx context.Context, envID envsec.EnvID, name string) ([]envsec.Version, error) {
+	return []envsec.Version{
+		{Number: 2, Value: "second-value", ModifiedAt: time.Now(), ModifiedBy: "alice"},
+		{Number: 1, Value: "first-value", ModifiedAt: time.Now().Add(-time.Hour), ModifiedBy: "bob"},
+	}, nil
+}
+
+func TestHistory(t *testing.T) {
+	envID := envsec.EnvID{ProjectID: "proj", OrgID: "org", EnvName: "dev"}
+	tests := []struct {
+		name        string
+		store       envsec.Store
+		args        []string
+		contains    []string
+		notContains []string
+		err         string
+	}{
+		{
+			name:        "masked",
+			store:       &versionedStore{memstore.New()},
+			contains:    []string{"alice", "bob", "se*****ue"},
+			notContains: []string{"second-value", "first-value"},
+		},
+		{
+			name:     "show values",
+			store:    &versionedStore{memstore.New()},
+			args:     []string{"--show-values"},
+			contains: []string{"second-value", "first-value"},
+		},
+		{
+			name:  "unsupported",
+			store: memstore.New(),
+			err:   "history isn't available",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := append([]string{"A"}, test.args...)
+			out, _, err := runWithStore(t, test.store, envID, HistoryCmd(), args...)
+
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("Expected an error containing %q, but got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, s := range test.contains {
+				if !strings.Contains(out, s) {
+					t.Errorf("Expected the output to contain %q:\n%s", s, out)
+				}
+			}
+			for _, s := range test.notContains {
+				if strings.Contains(out, s) {
+					t.Errorf("Expected the output not to contain %q:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
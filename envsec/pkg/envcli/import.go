@@ -0,0 +1,337 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"go.jetpack.io/envsec/internal/tux"
+)
+
+type importCmdFlags struct {
+	configFlags
+	format    string
+	dryRun    bool
+	overwrite bool
+	force     bool
+	keepGoing bool
+}
+
+func ImportCmd() *cobra.Command {
+	flags := &importCmdFlags{}
+	command := &cobra.Command{
+		Use:   "import [<file>]",
+		Short: "Import variables from a .env or JSON file",
+		Long: "Import variables from a .env or JSON file, or from stdin when no file (or -) is given. " +
+			".env files may contain comments, quoted values and `export` prefixes. " +
+			"Variables that already exist are left unchanged unless --overwrite is set. " +
+			"Names may only contain letters, digits and underscores, and can't start with a digit, unless --force is set. " +
+			"Names starting with JETPACK_ are reserved, even with --force. " +
+			"By default a malformed line or invalid name stops the import before anything is written. " +
+			"With --keep-going everything that can be imported is, and the lines and variables that failed " +
+			"are reported at the end with a non-zero exit status.",
+		Args: cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if flags.format == "json" || flags.format == "env" {
+				return nil
+			}
+			return errors.Wrapf(errUnsupportedFormat, "format: %s", flags.format)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := cmd.InOrStdin()
+			if len(args) == 1 && args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				defer f.Close()
+				in = f
+			}
+			envMap, failures, err := parseImport(in, flags.format, flags.keepGoing)
+			if err != nil {
+				return err
+			}
+			if flags.keepGoing {
+				for _, name := range sortedKeys(envMap) {
+					if err := validateName(name, flags.force); err != nil {
+						failures = append(failures, importFailure{name, err})
+						delete(envMap, name)
+					}
+				}
+			} else if err := ensureValidNames(lo.Keys(envMap), flags.force); err != nil {
+				return errors.WithStack(err)
+			}
+
+			cmdCfg, err := flags.genConfig(cmd)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			existing, err := cmdCfg.Store.List(cmd.Context(), cmdCfg.EnvID)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			plan := planImport(envVarMap(existing), envMap, flags.overwrite)
+
+			if flags.dryRun {
+				if err := plan.print(cmd.OutOrStdout()); err != nil {
+					return err
+				}
+				return reportImportFailures(cmd, failures)
+			}
+
+			toSet := map[string]string{}
+			for _, name := range append(plan.created, plan.updated...) {
+				toSet[name] = envMap[name]
+			}
+			if flags.keepGoing {
+				// Set the variables one by one, so that those that fail
+				// don't keep the others from being imported.
+				for _, name := range sortedKeys(toSet) {
+					err := cmdCfg.Store.Set(cmd.Context(), cmdCfg.EnvID, name, toSet[name])
+					if err != nil {
+						failures = append(failures, importFailure{name, err})
+						delete(toSet, name)
+					}
+				}
+			} else if len(toSet) > 0 {
+				err = cmdCfg.Store.SetAll(cmd.Context(), cmdCfg.EnvID, toSet)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+			}
+
+			summary := fmt.Sprintf(
+				"%d created, %d updated, %d skipped",
+				len(lo.Intersect(plan.created, lo.Keys(toSet))),
+				len(lo.Intersect(plan.updated, lo.Keys(toSet))),
+				len(plan.skipped),
+			)
+			if flags.keepGoing {
+				summary += fmt.Sprintf(", %d failed", len(failures))
+			}
+			err = tux.WriteHeader(cmd.OutOrStdout(),
+				"[DONE] Imported %d environment variable(s) to environment: %s (%s)\n",
+				len(toSet),
+				strings.ToLower(cmdCfg.EnvID.EnvName),
+				summary,
+			)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return reportImportFailures(cmd, failures)
+		},
+	}
+
+	command.Flags().StringVarP(
+		&flags.format, "format", "f", "env", "File format: env or json")
+	command.Flags().BoolVar(
+		&flags.dryRun, "dry-run", false, "Show what would change without writing anything")
+	command.Flags().BoolVar(
+		&flags.overwrite, "overwrite", false, "Replace the values of variables that already exist")
+	command.Flags().BoolVar(
+		&flags.force, "force", false, "Import variables even if their names aren't valid environment variable names")
+	command.Flags().BoolVar(
+		&flags.keepGoing, "keep-going", false, "Import the valid variables even if others fail, then report the failures")
+	flags.configFlags.register(command)
+
+	return command
+}
+
+// importFailure is a variable, or a line of a .env file, that couldn't be
+// imported.
+type importFailure struct {
+	key string
+	err error
+}
+
+// parseImport reads variables in format from r. With keepGoing, malformed
+// lines of a .env file, and values of a JSON object that aren't strings, are
+// returned as failures instead of failing the whole import.
+func parseImport(r io.Reader, format string, keepGoing bool) (map[string]string, []importFailure, error) {
+	if format == "json" {
+		if keepGoing {
+			return parseJSONKeepGoing(r)
+		}
+		envMap := map[string]string{}
+		if err := json.NewDecoder(r).Decode(&envMap); err != nil {
+			return nil, nil, errors.Wrap(
+				err,
+				"failed to load from JSON. Ensure the input is a flat key-value "+
+					"JSON object",
+			)
+		}
+		return envMap, nil, nil
+	}
+	if !keepGoing {
+		envMap, err := godotenv.Parse(r)
+		return envMap, nil, errors.WithStack(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	if envMap, err := godotenv.Parse(bytes.NewReader(data)); err == nil {
+		return envMap, nil, nil
+	}
+	return parseEnvLines(data)
+}
+
+// parseJSONKeepGoing reads a JSON object, returning the values that aren't
+// strings as failures.
+func parseJSONKeepGoing(r io.Reader) (map[string]string, []importFailure, error) {
+	raw := map[string]json.RawMessage{}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to load from JSON. Ensure the input is a JSON object")
+	}
+	envMap := map[string]string{}
+	failures := []importFailure{}
+	for _, name := range sortedKeys(raw) {
+		var value string
+		if err := json.Unmarshal(raw[name], &value); err != nil {
+			failures = append(failures, importFailure{name, errors.New("value must be a string")})
+			continue
+		}
+		envMap[name] = value
+	}
+	return envMap, failures, nil
+}
+
+// parseEnvLines parses a .env file that godotenv can't parse as a whole, one
+// entry at a time, returning the entries it can't parse as failures. An entry
+// is a line, or all the lines of a quoted value that spans several of them.
+func parseEnvLines(data []byte) (map[string]string, []importFailure, error) {
+	envMap := map[string]string{}
+	failures := []importFailure{}
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		start := i
+		i = quotedValueEnd(lines, start)
+		entryMap, err := godotenv.Unmarshal(strings.Join(lines[start:i+1], "\n"))
+		if err == nil && len(entryMap) == 0 {
+			err = errors.New("no variable found")
+		}
+		if err != nil {
+			failures = append(failures, importFailure{fmt.Sprintf("line %d", start+1), err})
+			continue
+		}
+		for name, value := range entryMap {
+			envMap[name] = value
+		}
+	}
+	return envMap, failures, nil
+}
+
+// quotedValueEnd returns the index of the line that closes the quoted value
+// opened on lines[start]. It returns start if the value isn't quoted, is
+// closed on the same line or is never closed, so that an unterminated quote
+// fails on its own instead of taking the rest of the file with it.
+func quotedValueEnd(lines []string, start int) int {
+	separator := strings.IndexAny(lines[start], "=:")
+	if separator == -1 {
+		return start
+	}
+	value := strings.TrimLeft(lines[start][separator+1:], " \t")
+	if value == "" || (value[0] != '"' && value[0] != '\'') {
+		return start
+	}
+	quote := value[0]
+	if closesQuote(value[1:], quote) {
+		return start
+	}
+	for end := start + 1; end < len(lines); end++ {
+		if closesQuote(lines[end], quote) {
+			return end
+		}
+	}
+	return start
+}
+
+// closesQuote reports whether s contains quote without a backslash before it,
+// which is where godotenv ends a quoted value.
+func closesQuote(s string, quote byte) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == quote && (i == 0 || s[i-1] != '\\') {
+			return true
+		}
+	}
+	return false
+}
+
+// reportImportFailures prints failures to stderr and returns an error if
+// there are any, so that envsec exits with a non-zero status.
+func reportImportFailures(cmd *cobra.Command, failures []importFailure) error {
+	for _, failure := range failures {
+		fmt.Fprintf(cmd.ErrOrStderr(), "failed  %s: %v\n", failure.key, failure.err)
+	}
+	if len(failures) > 0 {
+		return errors.Errorf("failed to import %d of the variables or lines, see above", len(failures))
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := lo.Keys(m)
+	slices.Sort(keys)
+	return keys
+}
+
+// importPlan sorts the names being imported by what will happen to them.
+// Names whose value is unchanged appear in none of the lists.
+type importPlan struct {
+	created []string
+	updated []string
+	skipped []string
+}
+
+func planImport(existing, incoming map[string]string, overwrite bool) importPlan {
+	plan := importPlan{}
+	names := lo.Keys(incoming)
+	slices.Sort(names)
+	for _, name := range names {
+		current, exists := existing[name]
+		switch {
+		case !exists:
+			plan.created = append(plan.created, name)
+		case current == incoming[name]:
+			// Already up to date.
+		case overwrite:
+			plan.updated = append(plan.updated, name)
+		default:
+			plan.skipped = append(plan.skipped, name)
+		}
+	}
+	return plan
+}
+
+func (p importPlan) print(w io.Writer) error {
+	for _, name := range p.created {
+		fmt.Fprintf(w, "create  %s\n", name)
+	}
+	for _, name := range p.updated {
+		fmt.Fprintf(w, "update  %s\n", name)
+	}
+	for _, name := range p.skipped {
+		fmt.Fprintf(w, "skip    %s (already set, use --overwrite to replace)\n", name)
+	}
+	return tux.WriteHeader(w,
+		"[DRY RUN] %d to create, %d to update, %d to skip\n",
+		len(p.created),
+		len(p.updated),
+		len(p.skipped),
+	)
+}
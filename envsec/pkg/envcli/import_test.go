@@ -0,0 +1,124 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"strings"
+	"testing"
+
+	"go.jetpack.io/envsec"
+	"go.jetpack.io/envsec/pkg/memstore"
+)
+
+func TestImportKeepGoing(t *testing.T) {
+	envID := envsec.EnvID{ProjectID: "proj", OrgID: "org", EnvName: "dev"}
+	input := "A=1\nB=\"unterminated\n1B=2\nC=3\nD=4\n"
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected map[string]string
+		errors   string
+	}{
+		{
+			name:     "fail fast",
+			expected: map[string]string{},
+		},
+		{
+			name:     "keep going",
+			args:     []string{"--keep-going"},
+			expected: map[string]string{"A": "1", "D": "4"},
+			errors: "failed  line 2: unterminated quoted value \"unterminated\n" +
+				"failed  1B: invalid variable name \"1B\": it can't start with a digit\n" +
+				"failed  C: throttled\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &failingSetStore{Store: memstore.New(), fail: "C"}
+			cmd := ImportCmd()
+			cmd.SetIn(strings.NewReader(input))
+			_, errOut, err := runWithStore(t, store, envID, cmd, test.args...)
+			if err == nil {
+				t.Error("Expected an error")
+			}
+
+			vars := store.Vars(envID)
+			if len(vars) != len(test.expected) {
+				t.Errorf("Expected %v to be imported, but got %v", test.expected, vars)
+			}
+			for name, value := range test.expected {
+				if vars[name] != value {
+					t.Errorf("Expected %s to be %q, but got %q", name, value, vars[name])
+				}
+			}
+			if errOut != test.errors {
+				t.Errorf("Expected errors:\n%s\nbut got:\n%s", test.errors, errOut)
+			}
+		})
+	}
+}
+
+func TestImportKeepGoingMultilineValue(t *testing.T) {
+	envID := envsec.EnvID{ProjectID: "proj", OrgID: "org", EnvName: "dev"}
+	input := "CERT=\"-----BEGIN\nMIIB=abc\n-----END\"\nA=1\nB=\"unterminated\nC=2\n"
+	store := memstore.New()
+	cmd := ImportCmd()
+	cmd.SetIn(strings.NewReader(input))
+	_, errOut, err := runWithStore(t, store, envID, cmd, "--keep-going")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	expected := map[string]string{"CERT": "-----BEGIN\nMIIB=abc\n-----END", "A": "1", "C": "2"}
+	vars := store.Vars(envID)
+	if len(vars) != len(expected) {
+		t.Errorf("Expected %v to be imported, but got %v", expected, vars)
+	}
+	for name, value := range expected {
+		if vars[name] != value {
+			t.Errorf("Expected %s to be %q, but got %q", name, value, vars[name])
+		}
+	}
+	if expected := "failed  line 5: unterminated quoted value \"unterminated\n"; errOut != expected {
+		t.Errorf("Expected errors:\n%s\nbut got:\n%s", expected, errOut)
+	}
+}
+
+func TestImportForceKeepsReservedNames(t *testing.T) {
+	envID := envsec.EnvID{ProjectID: "proj", OrgID: "org", EnvName: "dev"}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]string
+		err      bool
+	}{
+		{"invalid", "1A=1\nA.B=2\n", map[string]string{"1A": "1", "A.B": "2"}, false},
+		{"reserved", "A=1\nJETPACK_X=2\n", map[string]string{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := memstore.New()
+			cmd := ImportCmd()
+			cmd.SetIn(strings.NewReader(test.input))
+			_, _, err := runWithStore(t, store, envID, cmd, "--force")
+			if (err != nil) != test.err {
+				t.Fatalf("Expected an error: %v, but got %v", test.err, err)
+			}
+
+			vars := store.Vars(envID)
+			if len(vars) != len(test.expected) {
+				t.Errorf("Expected %v to be imported, but got %v", test.expected, vars)
+			}
+			for name, value := range test.expected {
+				if vars[name] != value {
+					t.Errorf("Expected %s to be %q, but got %q", name, value, vars[name])
+				}
+			}
+		})
+	}
+}
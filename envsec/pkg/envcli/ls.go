@@ -14,6 +14,7 @@ const environmentFlagName = "environment"
 type listCmdFlags struct {
 	configFlags
 	ShowValues bool
+	MaskAll    bool
 	Format     string
 }
 
@@ -24,8 +25,10 @@ func ListCmd() *cobra.Command {
 		Use:     "ls",
 		Aliases: []string{"list"},
 		Short:   "List all stored environment variables",
-		Long:    "List all stored environment variables. If no environment flag is provided, variables in all environments will be listed.",
-		Args:    cobra.NoArgs,
+		Long: "List all stored environment variables. If no environment flag is provided, variables in all environments will be listed. " +
+			"The values of secrets are masked unless --show-values is set. Values the store marks as plain configuration " +
+			"are shown, unless --mask-all is set.",
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			cmdCfg, err := flags.genConfig(cmd)
 			if err != nil {
@@ -44,7 +47,7 @@ func ListCmd() *cobra.Command {
 					return errors.WithStack(err)
 				}
 
-				err = printEnv(cmd, envID, envVars, flags.ShowValues, flags.Format)
+				err = printEnv(cmd, envID, envVars, flags.ShowValues, flags.MaskAll, flags.Format)
 				if err != nil {
 					return errors.WithStack(err)
 				}
@@ -55,11 +58,20 @@ func ListCmd() *cobra.Command {
 
 	command.Flags().BoolVarP(
 		&flags.ShowValues,
-		"show",
+		"show-values",
 		"s",
 		false,
 		"Display the value of each environment variable (secrets included)",
 	)
+	command.Flags().BoolVar(&flags.ShowValues, "show", false, "")
+	_ = command.Flags().MarkDeprecated("show", "use --show-values instead")
+	command.Flags().BoolVar(
+		&flags.MaskAll,
+		"mask-all",
+		false,
+		"Mask every value, including those the store marks as plain configuration",
+	)
+	command.MarkFlagsMutuallyExclusive("show-values", "mask-all")
 	command.Flags().StringVarP(
 		&flags.Format,
 		"format",
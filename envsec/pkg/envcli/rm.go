@@ -4,9 +4,13 @@
 package envcli
 
 import (
+	"bufio"
+	"fmt"
+	"io"
 	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 	"go.jetpack.io/envsec"
 	"go.jetpack.io/envsec/internal/tux"
@@ -14,44 +18,136 @@ import (
 
 type removeCmdFlags struct {
 	configFlags
+	all bool
+	yes bool
 }
 
 func RemoveCmd() *cobra.Command {
 	flags := &removeCmdFlags{}
 	command := &cobra.Command{
-		Use:   "rm <NAME1> [<NAME2>]...",
-		Short: "Delete one or more environment variables",
-		Long:  "Delete one or more environment variables that are stored.",
-		Args:  cobra.MinimumNArgs(1),
-		RunE: func(cmd *cobra.Command, envNames []string) error {
+		Use:     "rm <NAME1> [<NAME2>]...",
+		Aliases: []string{"unset"},
+		Short:   "Delete one or more environment variables",
+		Long: "Delete one or more environment variables that are stored. Names that aren't set are reported and skipped. " +
+			"Use --all to delete every variable in the environment, after confirming, or right away with --yes.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if flags.all {
+				if len(args) > 0 {
+					return errors.New("--all can't be combined with variable names")
+				}
+				return nil
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, names []string) error {
 			cmdCfg, err := flags.genConfig(cmd)
 			if err != nil {
 				return errors.WithStack(err)
 			}
-			err = cmdCfg.Store.DeleteAll(cmd.Context(), cmdCfg.EnvID, envNames)
-			if err == nil {
-				err = tux.WriteHeader(cmd.OutOrStdout(),
-					"[DONE] Deleted environment %s %v in environment: %s\n",
-					tux.Plural(envNames, "variable", "variables"),
-					strings.Join(tux.QuotedTerms(envNames), ", "),
-					strings.ToLower(cmdCfg.EnvID.EnvName),
+			envName := strings.ToLower(cmdCfg.EnvID.EnvName)
+
+			var existing []envsec.EnvVar
+			if flags.all {
+				existing, err = cmdCfg.Store.List(cmd.Context(), cmdCfg.EnvID)
+			} else {
+				existing, err = cmdCfg.Store.GetAll(cmd.Context(), cmdCfg.EnvID, names)
+			}
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			existingNames := lo.Uniq(lo.Map(existing, func(envVar envsec.EnvVar, _ int) string {
+				return envVar.Name
+			}))
+			absentNames, _ := lo.Difference(lo.Uniq(names), existingNames)
+
+			if flags.all && len(existingNames) > 0 && !flags.yes {
+				confirmed, err := confirm(cmd, fmt.Sprintf(
+					"Delete all %d %s in environment %s?",
+					len(existingNames),
+					tux.Plural(existingNames, "variable", "variables"),
+					envName,
+				))
+				if err != nil {
+					return err
+				}
+				if !confirmed {
+					return tux.WriteHeader(cmd.OutOrStdout(), "[CANCELLED] No variables were deleted\n")
+				}
+			}
+
+			if len(existingNames) > 0 {
+				err = cmdCfg.Store.DeleteAll(cmd.Context(), cmdCfg.EnvID, existingNames)
+				if err == nil {
+					err = tux.WriteHeader(cmd.OutOrStdout(),
+						"[DONE] Deleted environment %s %v in environment: %s\n",
+						tux.Plural(existingNames, "variable", "variables"),
+						strings.Join(tux.QuotedTerms(existingNames), ", "),
+						envName,
+					)
+				}
+				if errors.Is(err, envsec.FaultyParamError) {
+					err = tux.WriteHeader(cmd.OutOrStdout(),
+						"[CANCELLED] Could not delete variable '%v' in environment: %s.\n"+
+							"Please make sure all listed variables exist and you have proper permission to remove them.\n",
+						strings.Split(err.Error(), ":")[0],
+						envName,
+					)
+				}
+				if err != nil {
+					return errors.WithStack(err)
+				}
+			} else if flags.all {
+				return tux.WriteHeader(cmd.OutOrStdout(),
+					"[DONE] There are no environment variables to delete in environment: %s\n",
+					envName,
 				)
 			}
-			if errors.Is(err, envsec.FaultyParamError) {
+
+			if len(absentNames) > 0 {
 				err = tux.WriteHeader(cmd.OutOrStdout(),
-					"[CANCELLED] Could not delete variable '%v' in environment: %s.\n"+
-						"Please make sure all listed variables exist and you have proper permission to remove them.\n",
-					strings.Split(err.Error(), ":")[0],
-					strings.ToLower(cmdCfg.EnvID.EnvName),
+					"[SKIPPED] Environment %s %v %s not set in environment: %s\n",
+					tux.Plural(absentNames, "variable", "variables"),
+					strings.Join(tux.QuotedTerms(absentNames), ", "),
+					tux.Plural(absentNames, "is", "are"),
+					envName,
 				)
-			}
-			if err != nil {
-				return errors.WithStack(err)
+				if err != nil {
+					return errors.WithStack(err)
+				}
 			}
 			return nil
 		},
 	}
+	command.Flags().BoolVar(
+		&flags.all,
+		"all",
+		false,
+		"Delete all variables in the environment",
+	)
+	command.Flags().BoolVarP(
+		&flags.yes,
+		"yes",
+		"y",
+		false,
+		"Don't ask for confirmation before deleting all variables with --all",
+	)
 	flags.configFlags.register(command)
 
 	return command
 }
+
+// confirm asks the user a yes or no question on the terminal. It fails if
+// stdin isn't a terminal, since nobody could answer.
+func confirm(cmd *cobra.Command, question string) (bool, error) {
+	if !isTerminal(cmd.InOrStdin()) {
+		return false, errors.New("can't ask for confirmation without a terminal. Use --yes to skip it")
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s [y/N] ", question)
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	// Ending the input without an answer, as with Ctrl-D, means no.
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, errors.WithStack(err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
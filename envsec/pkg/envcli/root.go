@@ -28,6 +28,22 @@ func RootCmd(flags *rootCmdFlags) *cobra.Command {
 			Securely stores and retrieves environment variables on the cloud.
 			Environment variables are always encrypted, which makes it possible to
 			store values that contain passwords and other secrets.
+
+			The organization, project and environment can be committed to an
+			.envsec.json file, such as {"environment": "prod"}, in the repository.
+			envsec uses the nearest one in the working directory or its parents.
+			Each setting is taken from the first of these that sets it:
+			--org-id, --project-id and --environment flags; the ENVSEC_ORG_ID,
+			ENVSEC_PROJECT_ID and ENVSEC_ENVIRONMENT variables; .envsec.json;
+			and finally the logged in organization, the project set up by
+			envsec init and the dev environment.
+
+			To work offline, such as in tests, set ENVSEC_FILE_STORE to the path
+			of a local file to keep the variables in instead, and
+			ENVSEC_FILE_STORE_KEY to the hex-encoded 16, 24 or 32 byte AES key
+			that encrypts it, such as the output of openssl rand -hex 32. No
+			login is needed then, but an organization ID, which can be any ID
+			such as org_00000000000000000000000000, and a project ID must be set.
 		`),
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			if flags.jsonErrors {
@@ -52,13 +68,23 @@ func RootCmd(flags *rootCmdFlags) *cobra.Command {
 	command.Flag("json-errors").Hidden = true
 
 	command.AddCommand(authCmd())
+	command.AddCommand(cacheCmd())
+	command.AddCommand(CopyCmd())
+	command.AddCommand(DiffCmd())
 	command.AddCommand(DownloadCmd())
+	command.AddCommand(EnvsCmd())
 	command.AddCommand(ExecCmd())
+	command.AddCommand(ExportCmd())
 	command.AddCommand(genDocsCmd())
+	command.AddCommand(GetCmd())
+	command.AddCommand(HistoryCmd())
+	command.AddCommand(ImportCmd())
 	command.AddCommand(initCmd())
 	command.AddCommand(ListCmd())
 	command.AddCommand(RemoveCmd())
+	command.AddCommand(RotateCmd())
 	command.AddCommand(SetCmd())
+	command.AddCommand(TemplateCmd())
 	command.AddCommand(UploadCmd())
 	command.AddCommand(versionCmd())
 	command.SetUsageFunc(UsageFunc)
@@ -72,6 +98,11 @@ func Execute(ctx context.Context) int {
 	if err == nil {
 		return 0
 	}
+	// The command run by `envsec exec` (or envsec itself) has already reported
+	// the failure, so exit with its status instead of printing a generic error.
+	if code, ok := exitCode(err); ok {
+		return code
+	}
 	if flags.jsonErrors {
 		var jsonErr struct {
 			Error string `json:"error"`
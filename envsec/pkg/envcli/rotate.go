@@ -0,0 +1,81 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.jetpack.io/envsec/internal/tux"
+)
+
+type rotateCmdFlags struct {
+	configFlags
+	filterFlags
+}
+
+func RotateCmd() *cobra.Command {
+	flags := &rotateCmdFlags{}
+	command := &cobra.Command{
+		Use:   "rotate",
+		Short: "Re-encrypt the variables of an environment",
+		Long: "Re-encrypt every variable in an environment by writing its current value back to the store, " +
+			"which encrypts it again with the store's current key. Values are never changed, " +
+			"so it is safe to run again after some variables failed: the ones that succeeded are just rotated again. " +
+			"Use --only and --exclude to rotate some of the variables.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmdCfg, err := flags.genConfig(cmd)
+			if err != nil {
+				return err
+			}
+			envVars, err := cmdCfg.Store.List(cmd.Context(), cmdCfg.EnvID)
+			if err != nil {
+				return explainStoreError(err, cmdCfg.EnvID)
+			}
+			envVars, err = flags.filter(envVars)
+			if err != nil {
+				return err
+			}
+
+			// Set each variable on its own, so that one failure doesn't stop
+			// the others from being rotated.
+			failed := []string{}
+			for i, envVar := range envVars {
+				progress := fmt.Sprintf("[%d/%d]", i+1, len(envVars))
+				err := cmdCfg.Store.Set(cmd.Context(), cmdCfg.EnvID, envVar.Name, envVar.Value)
+				if err != nil {
+					failed = append(failed, envVar.Name)
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s failed  %s: %v\n", progress, envVar.Name, err)
+					continue
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s rotated %s\n", progress, envVar.Name)
+			}
+
+			envName := strings.ToLower(cmdCfg.EnvID.EnvName)
+			if len(failed) > 0 {
+				return errors.Errorf(
+					"failed to rotate %d of %d variable(s) in environment %s: %s. "+
+						"Run envsec rotate again to retry",
+					len(failed),
+					len(envVars),
+					envName,
+					strings.Join(failed, ", "),
+				)
+			}
+			return tux.WriteHeader(cmd.OutOrStdout(),
+				"[DONE] Rotated %d environment variable(s) in environment: %s\n",
+				len(envVars),
+				envName,
+			)
+		},
+	}
+
+	flags.configFlags.register(command)
+	flags.filterFlags.register(command)
+
+	return command
+}
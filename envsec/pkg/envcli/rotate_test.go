@@ -0,0 +1,57 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"go.jetpack.io/envsec"
+	"go.jetpack.io/envsec/pkg/memstore"
+)
+
+// failingSetStore records the variables it sets and fails to set one of them.
+type failingSetStore struct {
+	*memstore.Store
+	fail string
+	set  []string
+}
+
+func (s *failingSetStore) Set(ctx context.Context, envID envsec.EnvID, name string, value string) error {
+	if name == s.fail {
+		return errors.New("throttled")
+	}
+	s.set = append(s.set, name)
+	return s.Store.Set(ctx, envID, name, value)
+}
+
+func TestRotateContinuesPastFailures(t *testing.T) {
+	envID := envsec.EnvID{ProjectID: "proj", OrgID: "org", EnvName: "dev"}
+	values := map[string]string{"A": "1", "B": "2", "C": "3", "SKIPPED": "4"}
+	store := &failingSetStore{
+		Store: memstore.NewWithVars(map[envsec.EnvID]map[string]string{envID: values}),
+		fail:  "B",
+	}
+	out, errOut, err := runWithStore(t, store, envID, RotateCmd(), "--exclude", "SKIPPED")
+
+	if err == nil || !strings.Contains(err.Error(), "failed to rotate 1 of 3 variable(s) in environment dev: B") {
+		t.Errorf("Expected an error naming B, but got %v", err)
+	}
+	if strings.Join(store.set, ",") != "A,C" {
+		t.Errorf("Expected A and C to be rotated, but got %v", store.set)
+	}
+	if expected := "[1/3] rotated A\n[3/3] rotated C\n"; out != expected {
+		t.Errorf("Expected output %q, but got %q", expected, out)
+	}
+	if expected := "[2/3] failed  B: throttled\n"; errOut != expected {
+		t.Errorf("Expected errors %q, but got %q", expected, errOut)
+	}
+	for name, value := range store.Vars(envID) {
+		if values[name] != value {
+			t.Errorf("Expected %s to still be %q, but got %q", name, values[name], value)
+		}
+	}
+}
@@ -4,6 +4,8 @@
 package envcli
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -11,34 +13,68 @@ import (
 	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 	"go.jetpack.io/envsec/internal/tux"
+	"golang.org/x/term"
 )
 
 type setCmdFlags struct {
 	configFlags
+	stdin bool
+	force bool
 }
 
+// stdinValue is the value that stands for one read from stdin, as in NAME=-.
+const stdinValue = "-"
+
 func SetCmd() *cobra.Command {
 	flags := &setCmdFlags{}
 	command := &cobra.Command{
 		Use:   "set <NAME1>=<value1> [<NAME2>=<value2>]...",
 		Short: "Securely store one or more environment variables",
-		Long:  "Securely store one or more environment variables. To test contents of a file as a secret use set=@<file>",
-		Args:  cobra.MinimumNArgs(1),
+		Long: "Securely store one or more environment variables. To test contents of a file as a secret use set=@<file>. " +
+			"To keep a secret out of your shell history and the process list, give only its name, as in `envsec set NAME`: " +
+			"the value is then prompted for without being echoed, or read from stdin when it isn't a terminal, " +
+			"in which case an empty value is an error. " +
+			"--stdin always reads it from stdin, and so does a value of - (NAME=-), and both accept an empty value. " +
+			"A single trailing newline is removed from values read from stdin. " +
+			"Names may only contain letters, digits and underscores, and can't start with a digit, unless --force is set. " +
+			"Names starting with JETPACK_ are reserved, even with --force.",
+		Args: cobra.MinimumNArgs(1),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if flags.stdin || isBareName(args) {
+				if len(args) != 1 || strings.Contains(args[0], "=") {
+					return errors.New("--stdin takes a single variable name, without a value")
+				}
+				return nil
+			}
+			fromStdin := 0
 			for _, arg := range args {
-				k, _, ok := strings.Cut(arg, "=")
+				k, v, ok := strings.Cut(arg, "=")
 				if !ok || k == "" {
 					return errors.Errorf(
 						"argument %s must have an '=' to be of the form NAME=VALUE",
 						arg,
 					)
 				}
+				if v == stdinValue {
+					fromStdin++
+				}
+			}
+			if fromStdin > 1 {
+				return errors.New("only one variable can be read from stdin")
 			}
 
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			envMap, err := parseArgs(args)
+			var envMap map[string]string
+			var err error
+			if flags.stdin || isBareName(args) {
+				var value string
+				value, err = readValue(cmd, args[0], flags.stdin)
+				envMap = map[string]string{args[0]: value}
+			} else {
+				envMap, err = parseArgs(args, cmd.InOrStdin())
+			}
 			if err != nil {
 				return errors.WithStack(err)
 			}
@@ -46,7 +82,7 @@ func SetCmd() *cobra.Command {
 			if err != nil {
 				return errors.WithStack(err)
 			}
-			err = SetEnvMap(cmd.Context(), cmdCfg.Store, cmdCfg.EnvID, envMap)
+			err = setEnvMap(cmd.Context(), cmdCfg.Store, cmdCfg.EnvID, envMap, flags.force)
 			if err != nil {
 				return errors.WithStack(err)
 			}
@@ -64,14 +100,77 @@ func SetCmd() *cobra.Command {
 			return nil
 		},
 	}
+	command.Flags().BoolVar(
+		&flags.stdin,
+		"stdin",
+		false,
+		"Read the value of the variable from stdin",
+	)
+	command.Flags().BoolVar(
+		&flags.force,
+		"force",
+		false,
+		"Set variables even if their names aren't valid environment variable names",
+	)
 	flags.configFlags.register(command)
 	return command
 }
 
-func parseArgs(args []string) (map[string]string, error) {
+// isBareName reports whether args is a single variable name without a value,
+// which is then read from stdin or prompted for.
+func isBareName(args []string) bool {
+	return len(args) == 1 && !strings.Contains(args[0], "=")
+}
+
+// readValue reads the value of variable name from stdin. If stdin is a
+// terminal and fromStdin isn't set, the user is prompted for it instead, and
+// what they type isn't echoed. An empty value read from stdin is an error
+// unless fromStdin is set.
+func readValue(cmd *cobra.Command, name string, fromStdin bool) (string, error) {
+	stdin := cmd.InOrStdin()
+	if f, ok := stdin.(*os.File); ok && !fromStdin && isTerminal(f) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Value for %s: ", name)
+		value, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(cmd.ErrOrStderr())
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		return string(value), nil
+	}
+	value, err := readStdinValue(stdin)
+	if err == nil && value == "" && !fromStdin {
+		// Most likely stdin was left empty by mistake, as in a script.
+		return "", errors.Errorf("no value for %s was read from stdin, use --stdin to set it to an empty value", name)
+	}
+	return value, err
+}
+
+// readStdinValue reads a value from stdin, without the trailing newline that
+// commands such as echo add.
+func readStdinValue(stdin io.Reader) (string, error) {
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the value from stdin")
+	}
+	value, found := strings.CutSuffix(string(data), "\n")
+	if found {
+		value = strings.TrimSuffix(value, "\r")
+	}
+	return value, nil
+}
+
+func parseArgs(args []string, stdin io.Reader) (map[string]string, error) {
 	envMap := map[string]string{}
 	for _, arg := range args {
 		key, val, _ := strings.Cut(arg, "=")
+		if val == stdinValue {
+			value, err := readStdinValue(stdin)
+			if err != nil {
+				return nil, err
+			}
+			envMap[key] = value
+			continue
+		}
 		if strings.HasPrefix(val, "\\@") {
 			val = strings.TrimPrefix(val, "\\")
 		} else if strings.HasPrefix(val, "@") {
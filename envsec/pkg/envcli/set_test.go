@@ -0,0 +1,52 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"strings"
+	"testing"
+
+	"go.jetpack.io/envsec"
+	"go.jetpack.io/envsec/pkg/memstore"
+)
+
+func TestSetFromStdin(t *testing.T) {
+	envID := envsec.EnvID{ProjectID: "proj", OrgID: "org", EnvName: "dev"}
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		expected string
+		err      bool
+	}{
+		{"bare name", []string{"A"}, "secret\n", "secret", false},
+		{"bare name with empty stdin", []string{"A"}, "", "", true},
+		{"--stdin", []string{"--stdin", "A"}, "secret", "secret", false},
+		{"--stdin with empty stdin", []string{"--stdin", "A"}, "", "", false},
+		{"dash", []string{"A=-"}, "secret\r\n", "secret", false},
+		{"dash with empty stdin", []string{"A=-"}, "", "", false},
+		{"only one newline is trimmed", []string{"A"}, "secret\n\n", "secret\n", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := memstore.New()
+			cmd := SetCmd()
+			cmd.SetIn(strings.NewReader(test.stdin))
+			_, _, err := runWithStore(t, store, envID, cmd, test.args...)
+			if (err != nil) != test.err {
+				t.Fatalf("Expected an error: %v, but got %v", test.err, err)
+			}
+
+			value, ok := store.Vars(envID)["A"]
+			if ok == test.err {
+				t.Errorf("Expected A to be set: %v, but got %v", !test.err, ok)
+			}
+			if value != test.expected {
+				t.Errorf("Expected %q, but got %q", test.expected, value)
+			}
+		})
+	}
+}
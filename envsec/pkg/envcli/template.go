@@ -0,0 +1,71 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envcli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.jetpack.io/envsec/internal/tux"
+)
+
+type templateCmdFlags struct {
+	configFlags
+}
+
+func TemplateCmd() *cobra.Command {
+	flags := &templateCmdFlags{}
+	command := &cobra.Command{
+		Use:   "template <in> [<out>]",
+		Short: "Fill a template file with environment variables",
+		Long: "Render a Go text/template file with the stored environment variables, " +
+			"which are available as {{ .NAME }}. The result is written to <out>, " +
+			"or to stdout when <out> is omitted or -. Referencing a variable that " +
+			"isn't set is an error.",
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tmpl, err := template.New(filepath.Base(args[0])).
+				Option("missingkey=error").
+				ParseFiles(args[0])
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			cmdCfg, err := flags.genConfig(cmd)
+			if err != nil {
+				return err
+			}
+			envVars, err := cmdCfg.Store.List(cmd.Context(), cmdCfg.EnvID)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			// Render into memory first so a failed render doesn't leave a
+			// partially written output file behind.
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, envVarMap(envVars)); err != nil {
+				return errors.WithStack(err)
+			}
+
+			if len(args) == 1 || args[1] == "-" {
+				_, err = buf.WriteTo(cmd.OutOrStdout())
+				return errors.WithStack(err)
+			}
+			// The output contains secrets, so only the owner may read it.
+			if err := os.WriteFile(args[1], buf.Bytes(), 0o600); err != nil {
+				return errors.WithStack(err)
+			}
+			return tux.WriteHeader(cmd.OutOrStdout(),
+				"[DONE] Rendered %s to %s\n", args[0], args[1])
+		},
+	}
+
+	flags.configFlags.register(command)
+
+	return command
+}
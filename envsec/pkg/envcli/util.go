@@ -8,8 +8,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"regexp"
+	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/pkg/errors"
@@ -17,42 +18,75 @@ import (
 	"github.com/spf13/cobra"
 	"go.jetpack.io/envsec"
 	"go.jetpack.io/envsec/internal/tux"
+	"golang.org/x/term"
 )
 
-const nameRegexStr = "^[a-zA-Z_][a-zA-Z0-9_]*"
-
-var nameRegex = regexp.MustCompile(nameRegexStr)
-
 func SetEnvMap(ctx context.Context, store envsec.Store, envID envsec.EnvID, envMap map[string]string) error {
-	err := ensureValidNames(lo.Keys(envMap))
-	if err != nil {
+	return setEnvMap(ctx, store, envID, envMap, false /*force*/)
+}
+
+// setEnvMap is SetEnvMap, except that force skips most of the checks that the
+// names are valid variable names, as validateName does.
+func setEnvMap(
+	ctx context.Context,
+	store envsec.Store,
+	envID envsec.EnvID,
+	envMap map[string]string,
+	force bool,
+) error {
+	if err := ensureValidNames(lo.Keys(envMap), force); err != nil {
 		return errors.WithStack(err)
 	}
 
-	err = store.SetAll(ctx, envID, envMap)
+	err := store.SetAll(ctx, envID, envMap)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 	return nil
 }
 
-func ensureValidNames(names []string) error {
+// ensureValidNames returns an error for the first of names, in sorted order,
+// that isn't a valid variable name. force is passed on to validateName.
+func ensureValidNames(names []string, force bool) error {
+	names = slices.Clone(names)
+	slices.Sort(names)
 	for _, name := range names {
-
-		// Any variation of jetpack_ or JETPACK_ prefix is not allowed
-		lowerName := strings.ToLower(name)
-		if strings.HasPrefix(lowerName, "jetpack_") {
-			return errors.Errorf(
-				"name %s cannot start with JETPACK_ (or lowercase)",
-				name,
-			)
+		if err := validateName(name, force); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		if !nameRegex.MatchString(name) {
+// validateName checks that name can be passed to a child process as an
+// environment variable: letters, digits and underscores, not starting with a
+// digit. Names starting with JETPACK_ are reserved. force skips every check
+// but those for empty and reserved names.
+func validateName(name string, force bool) error {
+	if name == "" {
+		return errors.New("variable name can't be empty")
+	}
+
+	// Any variation of jetpack_ or JETPACK_ prefix is not allowed
+	if strings.HasPrefix(strings.ToLower(name), "jetpack_") {
+		return errors.Errorf(
+			"name %s cannot start with JETPACK_ (or lowercase)",
+			name,
+		)
+	}
+	if force {
+		return nil
+	}
+
+	if name[0] >= '0' && name[0] <= '9' {
+		return errors.Errorf("invalid variable name %q: it can't start with a digit", name)
+	}
+	for _, r := range name {
+		if r != '_' && !('a' <= r && r <= 'z') && !('A' <= r && r <= 'Z') && !('0' <= r && r <= '9') {
 			return errors.Errorf(
-				"name %s must match the regular expression: %s ",
+				"invalid variable name %q: %q isn't allowed, only letters, digits and underscores are",
 				name,
-				nameRegexStr,
+				r,
 			)
 		}
 	}
@@ -64,20 +98,13 @@ func printEnv(
 	envID envsec.EnvID,
 	envVars []envsec.EnvVar, // list of (name, value) pairs
 	flagPrintValues bool,
+	flagMaskAll bool,
 	flagFormat string,
 ) error {
-	envVarsMaskedValue := []envsec.EnvVar{}
+	envVarsMaskedValue := envVars
 	// Masking envVar values if printValue flag isn't set
-	for _, envVar := range envVars {
-		valueToPrint := "*****"
-		if flagPrintValues {
-			valueToPrint = envVar.Value
-		}
-		envVarsMaskedValue = append(envVarsMaskedValue, envsec.EnvVar{
-			Name:  envVar.Name,
-			Value: valueToPrint,
-		})
-
+	if !flagPrintValues {
+		envVarsMaskedValue = maskValues(envVars, flagMaskAll)
 	}
 
 	switch flagFormat {
@@ -132,8 +159,19 @@ func printDotenvFormat(envVars []envsec.EnvVar) error {
 	return nil
 }
 
+// jsonEnvVar is a variable as printed by ls --format json. It leaves out
+// EnvVar's Sensitivity, which isn't part of that output.
+type jsonEnvVar struct {
+	Name  string
+	Value string
+}
+
 func printJSONFormat(envVars []envsec.EnvVar) error {
-	data, err := json.MarshalIndent(envVars, "", "  ")
+	jsonEnvVars := make([]jsonEnvVar, 0, len(envVars))
+	for _, envVar := range envVars {
+		jsonEnvVars = append(jsonEnvVars, jsonEnvVar{Name: envVar.Name, Value: envVar.Value})
+	}
+	data, err := json.MarshalIndent(jsonEnvVars, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -142,6 +180,74 @@ func printJSONFormat(envVars []envsec.EnvVar) error {
 	return nil
 }
 
+const (
+	valueMask = "*****"
+	// Values shorter than this are masked entirely. Longer ones keep a few
+	// characters at each end so different values can be told apart.
+	minPartiallyMaskedLength = 12
+	unmaskedEdgeLength       = 2
+)
+
+// maskValue hides a secret value for display. The mask has a fixed length so
+// it doesn't reveal how long the value is. Empty values stay empty, since
+// there is nothing to hide.
+func maskValue(value string) string {
+	length := utf8.RuneCountInString(value)
+	switch {
+	case length == 0:
+		return ""
+	case length < minPartiallyMaskedLength:
+		return valueMask
+	default:
+		runes := []rune(value)
+		return string(runes[:unmaskedEdgeLength]) +
+			valueMask +
+			string(runes[length-unmaskedEdgeLength:])
+	}
+}
+
+// maskValues masks the values of secrets, and with all set, of plain
+// configuration too.
+func maskValues(envVars []envsec.EnvVar, all bool) []envsec.EnvVar {
+	masked := make([]envsec.EnvVar, 0, len(envVars))
+	for _, envVar := range envVars {
+		if all || envVar.IsSecret() {
+			envVar.Value = maskValue(envVar.Value)
+		}
+		masked = append(masked, envVar)
+	}
+	return masked
+}
+
+// explainStoreError adds what the user can do about err to its message, for
+// the classes of store errors they can fix themselves.
+func explainStoreError(err error, envID envsec.EnvID) error {
+	switch {
+	case errors.Is(err, envsec.ErrUnauthorized):
+		return errors.Wrap(
+			err,
+			"not authorized to access the stored variables. Log in again with `envsec auth login`, "+
+				"or check that your credentials have access to this project",
+		)
+	case errors.Is(err, envsec.ErrEnvNotFound):
+		return errors.Wrapf(
+			err,
+			"environment %s of project %s was not found. Check --environment and --project-id, "+
+				"or run `envsec init` to set up this directory",
+			strings.ToLower(envID.EnvName),
+			envID.ProjectID,
+		)
+	}
+	return err
+}
+
+// isTerminal reports whether stream, such as a command's stdin or stdout, is
+// a terminal, as opposed to a file or a pipe.
+func isTerminal(stream any) bool {
+	f, ok := stream.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
 func fileExists(path string) (bool, error) {
 	fileinfo, err := os.Stat(path)
 	if err == nil {
@@ -1,3 +1,5 @@
 // package envsec contains library functions for working with envsec.
 // if you want to include envsec in your own project, use this package.
+// The stores themselves, and ResolvedEnv for running a process with the
+// stored variables, are in the go.jetpack.io/envsec package.
 package envsec
@@ -0,0 +1,254 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+// Package filestore implements an envsec.Store that keeps variables in an
+// encrypted file on local disk, for development and tests that shouldn't
+// depend on a remote service.
+package filestore
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.jetpack.io/envsec"
+)
+
+// formatVersion is the version of the file format this package writes. Files
+// with a newer version are refused rather than misread.
+const formatVersion = 1
+
+// envelope is what is stored on disk: the encrypted contents, and the format
+// version they were written with.
+type envelope struct {
+	Version    int    `json:"version"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// contents are the decrypted variables, keyed by environment and then name.
+type contents struct {
+	Environments map[string]map[string]string `json:"environments"`
+}
+
+// Store is an envsec.Store backed by a single file encrypted with AES-GCM.
+// It is safe for concurrent use within a process, but not by several
+// processes writing at once.
+type Store struct {
+	path string
+	aead cipher.AEAD
+	mu   sync.Mutex
+}
+
+// Store implements interface envsec.Store (compile-time check)
+var _ envsec.Store = (*Store)(nil)
+
+// Store implements interface envsec.EnvNameLister (compile-time check)
+var _ envsec.EnvNameLister = (*Store)(nil)
+
+// New returns a Store that keeps its variables in the file at path, which is
+// created on the first write. key is an AES key of 16, 24 or 32 bytes.
+func New(path string, key []byte) (*Store, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Store{path: path, aead: aead}, nil
+}
+
+func (s *Store) List(ctx context.Context, envID envsec.EnvID) ([]envsec.EnvVar, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return envsec.SortedEnvVars(c.Environments[envKey(envID)]), nil
+}
+
+func (s *Store) ListEnvNames(ctx context.Context, envID envsec.EnvID) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	prefix := envKey(envsec.EnvID{OrgID: envID.OrgID, ProjectID: envID.ProjectID})
+	envNames := []string{}
+	for key, vars := range c.Environments {
+		if envName, ok := strings.CutPrefix(key, prefix); ok && len(vars) > 0 {
+			envNames = append(envNames, envName)
+		}
+	}
+	slices.Sort(envNames)
+	return envNames, nil
+}
+
+func (s *Store) Get(ctx context.Context, envID envsec.EnvID, name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	value, ok := c.Environments[envKey(envID)][name]
+	if !ok {
+		return "", envsec.KeyNotFound(name)
+	}
+	return value, nil
+}
+
+func (s *Store) GetAll(ctx context.Context, envID envsec.EnvID, names []string) ([]envsec.EnvVar, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	vars := c.Environments[envKey(envID)]
+	return envsec.SortedEnvVars(lo.PickByKeys(vars, names)), nil
+}
+
+func (s *Store) Set(ctx context.Context, envID envsec.EnvID, name string, value string) error {
+	return s.SetAll(ctx, envID, map[string]string{name: value})
+}
+
+func (s *Store) SetAll(ctx context.Context, envID envsec.EnvID, values map[string]string) error {
+	return s.update(func(c *contents) {
+		key := envKey(envID)
+		if c.Environments[key] == nil {
+			c.Environments[key] = map[string]string{}
+		}
+		for name, value := range values {
+			c.Environments[key][name] = value
+		}
+	})
+}
+
+func (s *Store) Delete(ctx context.Context, envID envsec.EnvID, name string) error {
+	return s.DeleteAll(ctx, envID, []string{name})
+}
+
+func (s *Store) DeleteAll(ctx context.Context, envID envsec.EnvID, names []string) error {
+	return s.update(func(c *contents) {
+		key := envKey(envID)
+		for _, name := range names {
+			delete(c.Environments[key], name)
+		}
+		if len(c.Environments[key]) == 0 {
+			delete(c.Environments, key)
+		}
+	})
+}
+
+// update applies change to the contents of the file and writes them back.
+func (s *Store) update(change func(c *contents)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.read()
+	if err != nil {
+		return err
+	}
+	change(c)
+	return s.write(c)
+}
+
+// read decrypts the file. A file that doesn't exist yet has no variables.
+func (s *Store) read() (*contents, error) {
+	c := &contents{Environments: map[string]map[string]string{}}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	env := &envelope{}
+	if err := json.Unmarshal(data, env); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", s.path)
+	}
+	if env.Version > formatVersion {
+		return nil, errors.Errorf(
+			"%s was written by a newer version of envsec (format %d, this version reads up to %d)",
+			s.path,
+			env.Version,
+			formatVersion,
+		)
+	}
+	plaintext, err := s.aead.Open(nil, env.Nonce, env.Ciphertext, additionalData(env.Version))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decrypt %s. Is the key right?", s.path)
+	}
+	if err := json.Unmarshal(plaintext, c); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", s.path)
+	}
+	if c.Environments == nil {
+		c.Environments = map[string]map[string]string{}
+	}
+	return c, nil
+}
+
+// write encrypts c into the file. It writes a temporary file first and
+// renames it into place, so the file is never left half written.
+func (s *Store) write(c *contents) error {
+	plaintext, err := json.Marshal(c)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return errors.WithStack(err)
+	}
+	data, err := json.Marshal(&envelope{
+		Version:    formatVersion,
+		Nonce:      nonce,
+		Ciphertext: s.aead.Seal(nil, nonce, plaintext, additionalData(formatVersion)),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return errors.WithStack(err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.WithStack(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmp.Name(), s.path))
+}
+
+// additionalData binds the ciphertext to the format version, so that the
+// version can't be changed without the key.
+func additionalData(version int) []byte {
+	return []byte("envsec-filestore-v" + strconv.Itoa(version))
+}
+
+func envKey(envID envsec.EnvID) string {
+	return envID.OrgID + "/" + envID.ProjectID + "/" + envID.EnvName
+}
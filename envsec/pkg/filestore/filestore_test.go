@@ -0,0 +1,102 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"go.jetpack.io/envsec"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vars.json")
+	dev := envsec.EnvID{OrgID: "org", ProjectID: "proj", EnvName: "dev"}
+	prod := envsec.EnvID{OrgID: "org", ProjectID: "proj", EnvName: "prod"}
+
+	store, err := New(path, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetAll(ctx, dev, map[string]string{"B": "two", "A": "one\nline", "C": ""}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, prod, "A", "prod"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, dev, "B"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new Store reads back what the first one wrote.
+	store, err = New(path, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars, err := store.List(ctx, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []envsec.EnvVar{{Name: "A", Value: "one\nline"}, {Name: "C", Value: ""}}
+	if len(vars) != len(expected) || vars[0] != expected[0] || vars[1] != expected[1] {
+		t.Errorf("Expected %v, but got %v", expected, vars)
+	}
+	if value, err := store.Get(ctx, prod, "A"); err != nil || value != "prod" {
+		t.Errorf("Expected prod, but got %q, %v", value, err)
+	}
+	if _, err := store.Get(ctx, dev, "B"); !errors.Is(err, envsec.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, but got %v", err)
+	}
+	envNames, err := store.ListEnvNames(ctx, dev)
+	if err != nil || strings.Join(envNames, ",") != "dev,prod" {
+		t.Errorf("Expected dev and prod, but got %v, %v", envNames, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("prod")) || bytes.Contains(data, []byte("line")) {
+		t.Error("Expected the file to be encrypted")
+	}
+}
+
+func TestWrongKey(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vars.json")
+	envID := envsec.EnvID{OrgID: "org", ProjectID: "proj", EnvName: "dev"}
+	store, _ := New(path, testKey)
+	if err := store.Set(ctx, envID, "A", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	other, _ := New(path, bytes.Repeat([]byte{8}, 32))
+	if _, err := other.List(ctx, envID); err == nil {
+		t.Error("Expected an error reading with the wrong key")
+	}
+}
+
+func TestNewerFormatVersion(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vars.json")
+	data, _ := json.Marshal(&envelope{Version: formatVersion + 1})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, _ := New(path, testKey)
+	_, err := store.List(ctx, envsec.EnvID{})
+	if err == nil || !strings.Contains(err.Error(), "newer version") {
+		t.Errorf("Expected an error about a newer version, but got %v", err)
+	}
+}
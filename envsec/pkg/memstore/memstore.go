@@ -0,0 +1,139 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+// Package memstore implements an envsec.Store that keeps variables in memory,
+// for tests and for programs that embed envsec without a remote service.
+package memstore
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/samber/lo"
+	"go.jetpack.io/envsec"
+)
+
+// Store is an envsec.Store backed by a map keyed by EnvID. It is safe for
+// concurrent use. The zero value is an empty store ready to use.
+type Store struct {
+	mu   sync.RWMutex
+	envs map[envsec.EnvID]map[string]string
+}
+
+// Store implements interface envsec.Store (compile-time check)
+var _ envsec.Store = (*Store)(nil)
+
+// Store implements interface envsec.EnvNameLister (compile-time check)
+var _ envsec.EnvNameLister = (*Store)(nil)
+
+// Store implements interface envsec.NameLister (compile-time check)
+var _ envsec.NameLister = (*Store)(nil)
+
+// New returns an empty Store.
+func New() *Store {
+	return &Store{}
+}
+
+// NewWithVars returns a Store preloaded with vars, keyed by environment and
+// then name.
+func NewWithVars(vars map[envsec.EnvID]map[string]string) *Store {
+	s := New()
+	for envID, values := range vars {
+		s.Load(envID, values)
+	}
+	return s
+}
+
+// Load sets values in the environment envID, like SetAll but without a
+// context or an error, so that tests can preload a store in one line.
+func (s *Store) Load(envID envsec.EnvID, values map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.envs == nil {
+		s.envs = map[envsec.EnvID]map[string]string{}
+	}
+	if s.envs[envID] == nil {
+		s.envs[envID] = map[string]string{}
+	}
+	for name, value := range values {
+		s.envs[envID][name] = value
+	}
+}
+
+// Vars returns a copy of the variables in the environment envID, so that
+// tests can check what a command stored.
+func (s *Store) Vars(envID envsec.EnvID) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return lo.Assign(s.envs[envID])
+}
+
+func (s *Store) List(ctx context.Context, envID envsec.EnvID) ([]envsec.EnvVar, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return envsec.SortedEnvVars(s.envs[envID]), nil
+}
+
+func (s *Store) ListNames(ctx context.Context, envID envsec.EnvID) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := lo.Keys(s.envs[envID])
+	slices.Sort(names)
+	return names, nil
+}
+
+func (s *Store) ListEnvNames(ctx context.Context, envID envsec.EnvID) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	envNames := []string{}
+	for id, vars := range s.envs {
+		if id.OrgID == envID.OrgID && id.ProjectID == envID.ProjectID && len(vars) > 0 {
+			envNames = append(envNames, id.EnvName)
+		}
+	}
+	slices.Sort(envNames)
+	return envNames, nil
+}
+
+func (s *Store) Get(ctx context.Context, envID envsec.EnvID, name string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.envs[envID][name]
+	if !ok {
+		return "", envsec.KeyNotFound(name)
+	}
+	return value, nil
+}
+
+func (s *Store) GetAll(ctx context.Context, envID envsec.EnvID, names []string) ([]envsec.EnvVar, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return envsec.SortedEnvVars(lo.PickByKeys(s.envs[envID], names)), nil
+}
+
+func (s *Store) Set(ctx context.Context, envID envsec.EnvID, name string, value string) error {
+	s.Load(envID, map[string]string{name: value})
+	return nil
+}
+
+func (s *Store) SetAll(ctx context.Context, envID envsec.EnvID, values map[string]string) error {
+	s.Load(envID, values)
+	return nil
+}
+
+func (s *Store) Delete(ctx context.Context, envID envsec.EnvID, name string) error {
+	return s.DeleteAll(ctx, envID, []string{name})
+}
+
+func (s *Store) DeleteAll(ctx context.Context, envID envsec.EnvID, names []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range names {
+		delete(s.envs[envID], name)
+	}
+	if len(s.envs[envID]) == 0 {
+		delete(s.envs, envID)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package memstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"go.jetpack.io/envsec"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	dev := envsec.EnvID{OrgID: "org", ProjectID: "proj", EnvName: "dev"}
+	prod := envsec.EnvID{OrgID: "org", ProjectID: "proj", EnvName: "prod"}
+	store := NewWithVars(map[envsec.EnvID]map[string]string{
+		dev:  {"B": "two", "A": "one"},
+		prod: {"A": "prod"},
+	})
+
+	if err := store.Set(ctx, dev, "C", "three"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, dev, "B"); err != nil {
+		t.Fatal(err)
+	}
+	vars, _ := store.List(ctx, dev)
+	expected := []envsec.EnvVar{{Name: "A", Value: "one"}, {Name: "C", Value: "three"}}
+	if len(vars) != len(expected) || vars[0] != expected[0] || vars[1] != expected[1] {
+		t.Errorf("Expected %v, but got %v", expected, vars)
+	}
+	if _, err := store.Get(ctx, dev, "B"); !errors.Is(err, envsec.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, but got %v", err)
+	}
+
+	// Deleting the last variable removes the environment.
+	if err := store.DeleteAll(ctx, prod, []string{"A"}); err != nil {
+		t.Fatal(err)
+	}
+	envNames, _ := store.ListEnvNames(ctx, dev)
+	if len(envNames) != 1 || envNames[0] != "dev" {
+		t.Errorf("Expected only dev, but got %v", envNames)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envsec
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/aws/smithy-go"
+	"github.com/pkg/errors"
+)
+
+// RetryOptions configures a RetryStore.
+type RetryOptions struct {
+	// Number of times a failed call is retried. Zero disables retrying.
+	Retries int
+	// Delay before the first retry. It doubles with every attempt. Defaults
+	// to 200ms.
+	BaseDelay time.Duration
+	// Upper bound on the delay between attempts. Defaults to 5s.
+	MaxDelay time.Duration
+}
+
+// RetryStore wraps a Store and retries calls that fail with transient errors,
+// such as network failures and throttling, with exponential backoff and
+// jitter. Errors that won't go away by retrying, such as authentication
+// failures, are returned right away.
+type RetryStore struct {
+	store Store
+	opts  RetryOptions
+
+	// Overridden in tests.
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func(d time.Duration) time.Duration
+}
+
+// RetryStore implements interface Store (compile-time check)
+var _ Store = (*RetryStore)(nil)
+
+// RetryStore implements interface EnvNameLister (compile-time check)
+var _ EnvNameLister = (*RetryStore)(nil)
+
+// RetryStore implements interface NameLister (compile-time check)
+var _ NameLister = (*RetryStore)(nil)
+
+// RetryStore implements interface Versioned (compile-time check)
+var _ Versioned = (*RetryStore)(nil)
+
+func NewRetryStore(store Store, opts RetryOptions) *RetryStore {
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = 200 * time.Millisecond
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 5 * time.Second
+	}
+	return &RetryStore{
+		store:  store,
+		opts:   opts,
+		sleep:  sleepContext,
+		jitter: equalJitter,
+	}
+}
+
+func (r *RetryStore) List(ctx context.Context, envID EnvID) ([]EnvVar, error) {
+	var vars []EnvVar
+	err := r.retry(ctx, func() (err error) {
+		vars, err = r.store.List(ctx, envID)
+		return err
+	})
+	return vars, err
+}
+
+func (r *RetryStore) Get(ctx context.Context, envID EnvID, name string) (string, error) {
+	var value string
+	err := r.retry(ctx, func() (err error) {
+		value, err = r.store.Get(ctx, envID, name)
+		return err
+	})
+	return value, err
+}
+
+func (r *RetryStore) GetAll(ctx context.Context, envID EnvID, names []string) ([]EnvVar, error) {
+	var vars []EnvVar
+	err := r.retry(ctx, func() (err error) {
+		vars, err = r.store.GetAll(ctx, envID, names)
+		return err
+	})
+	return vars, err
+}
+
+func (r *RetryStore) Set(ctx context.Context, envID EnvID, name string, value string) error {
+	return r.retry(ctx, func() error {
+		return r.store.Set(ctx, envID, name, value)
+	})
+}
+
+func (r *RetryStore) SetAll(ctx context.Context, envID EnvID, values map[string]string) error {
+	return r.retry(ctx, func() error {
+		return r.store.SetAll(ctx, envID, values)
+	})
+}
+
+func (r *RetryStore) Delete(ctx context.Context, envID EnvID, name string) error {
+	return r.retry(ctx, func() error {
+		return r.store.Delete(ctx, envID, name)
+	})
+}
+
+func (r *RetryStore) DeleteAll(ctx context.Context, envID EnvID, names []string) error {
+	return r.retry(ctx, func() error {
+		return r.store.DeleteAll(ctx, envID, names)
+	})
+}
+
+func (r *RetryStore) ListEnvNames(ctx context.Context, envID EnvID) ([]string, error) {
+	var names []string
+	err := r.retry(ctx, func() (err error) {
+		names, err = ListEnvNames(ctx, r.store, envID)
+		return err
+	})
+	return names, err
+}
+
+func (r *RetryStore) ListNames(ctx context.Context, envID EnvID) ([]string, error) {
+	var names []string
+	err := r.retry(ctx, func() (err error) {
+		names, err = ListNames(ctx, r.store, envID)
+		return err
+	})
+	return names, err
+}
+
+func (r *RetryStore) History(ctx context.Context, envID EnvID, name string) ([]Version, error) {
+	var versions []Version
+	err := r.retry(ctx, func() (err error) {
+		versions, err = History(ctx, r.store, envID, name)
+		return err
+	})
+	return versions, err
+}
+
+// retry calls fn until it succeeds, fails with an error that isn't
+// transient, or has been retried opts.Retries times.
+func (r *RetryStore) retry(ctx context.Context, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= r.opts.Retries || !IsTransient(err) {
+			return err
+		}
+		if sleepErr := r.sleep(ctx, r.jitter(r.delay(attempt))); sleepErr != nil {
+			// Cancelled while waiting. The last failure is more useful to
+			// report than the cancellation.
+			return err
+		}
+	}
+}
+
+// delay returns the backoff before retry number attempt+1, before jitter.
+func (r *RetryStore) delay(attempt int) time.Duration {
+	d := r.opts.BaseDelay
+	for i := 0; i < attempt && d < r.opts.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, r.opts.MaxDelay)
+}
+
+// equalJitter picks a random duration in [d/2, d], so that many clients
+// failing at once don't all retry at the same moment, while each still waits
+// at least half the backoff.
+func equalJitter(d time.Duration) time.Duration {
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// AWS error codes that mean the request may succeed if sent again.
+var transientAWSErrorCodes = map[string]bool{
+	"ThrottlingException":     true,
+	"TooManyUpdates":          true,
+	"RequestLimitExceeded":    true,
+	"InternalServerError":     true,
+	"ServiceUnavailable":      true,
+	"RequestTimeout":          true,
+	"RequestTimeoutException": true,
+}
+
+// IsTransient reports whether err is likely to go away if the call that
+// returned it is retried.
+func IsTransient(err error) bool {
+	// The caller gave up, so retrying can't help.
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	// Nor can it fix credentials or create what doesn't exist.
+	if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrEnvNotFound) || errors.Is(err, ErrKeyNotFound) {
+		return false
+	}
+
+	var connectErr *connect.Error
+	if errors.As(err, &connectErr) {
+		switch connectErr.Code() {
+		case connect.CodeUnavailable, connect.CodeResourceExhausted, connect.CodeAborted:
+			return true
+		}
+		return false
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return transientAWSErrorCodes[apiErr.ErrorCode()] || apiErr.ErrorFault() == smithy.FaultServer
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
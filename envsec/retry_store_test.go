@@ -0,0 +1,156 @@
+// Copyright 2024 Jetpack Technologies Inc and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package envsec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/aws/smithy-go"
+	"github.com/pkg/errors"
+)
+
+// flakyStore fails List with each of errs in turn, then succeeds.
+type flakyStore struct {
+	Store
+	errs  []error
+	calls int
+}
+
+func (s *flakyStore) List(ctx context.Context, envID EnvID) ([]EnvVar, error) {
+	s.calls++
+	if s.calls <= len(s.errs) {
+		return nil, s.errs[s.calls-1]
+	}
+	return []EnvVar{{Name: "FOO", Value: "bar"}}, nil
+}
+
+func newTestRetryStore(store Store, retries int) (*RetryStore, *[]time.Duration) {
+	r := NewRetryStore(store, RetryOptions{
+		Retries:   retries,
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  time.Second,
+	})
+	slept := &[]time.Duration{}
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		*slept = append(*slept, d)
+		return ctx.Err()
+	}
+	r.jitter = func(d time.Duration) time.Duration { return d }
+	return r, slept
+}
+
+func TestRetryStoreRetriesTransientErrors(t *testing.T) {
+	unavailable := connect.NewError(connect.CodeUnavailable, errors.New("down"))
+	store := &flakyStore{errs: []error{unavailable, io.ErrUnexpectedEOF}}
+	r, slept := newTestRetryStore(store, 3)
+
+	vars, err := r.List(context.Background(), EnvID{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(vars) != 1 || store.calls != 3 {
+		t.Errorf("Expected 1 variable after 3 calls, but got %d after %d", len(vars), store.calls)
+	}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+	if fmt.Sprint(*slept) != fmt.Sprint(expected) {
+		t.Errorf("Expected delays %v, but got %v", expected, *slept)
+	}
+}
+
+func TestRetryStoreGivesUp(t *testing.T) {
+	unavailable := connect.NewError(connect.CodeUnavailable, errors.New("down"))
+	store := &flakyStore{errs: []error{unavailable, unavailable, unavailable}}
+	r, _ := newTestRetryStore(store, 2)
+
+	if _, err := r.List(context.Background(), EnvID{}); !errors.Is(err, unavailable) {
+		t.Errorf("Expected the last error, but got %v", err)
+	}
+	if store.calls != 3 {
+		t.Errorf("Expected 3 calls, but got %d", store.calls)
+	}
+}
+
+func TestRetryStoreDoesNotRetryPermanentErrors(t *testing.T) {
+	unauthenticated := connect.NewError(connect.CodeUnauthenticated, errors.New("bad token"))
+	store := &flakyStore{errs: []error{unauthenticated}}
+	r, slept := newTestRetryStore(store, 3)
+
+	if _, err := r.List(context.Background(), EnvID{}); !errors.Is(err, unauthenticated) {
+		t.Errorf("Expected %v, but got %v", unauthenticated, err)
+	}
+	if store.calls != 1 || len(*slept) != 0 {
+		t.Errorf("Expected a single call and no delay, but got %d calls and %v", store.calls, *slept)
+	}
+}
+
+func TestRetryStoreStopsWhenCancelled(t *testing.T) {
+	unavailable := connect.NewError(connect.CodeUnavailable, errors.New("down"))
+	store := &flakyStore{errs: []error{unavailable, unavailable}}
+	r, _ := newTestRetryStore(store, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := r.List(ctx, EnvID{}); !errors.Is(err, unavailable) {
+		t.Errorf("Expected %v, but got %v", unavailable, err)
+	}
+	if store.calls != 1 {
+		t.Errorf("Expected 1 call, but got %d", store.calls)
+	}
+}
+
+func TestRetryStoreDelay(t *testing.T) {
+	r := NewRetryStore(nil, RetryOptions{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second})
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for attempt, want := range expected {
+		if got := r.delay(attempt); got != want {
+			t.Errorf("attempt %d: expected %v, but got %v", attempt, want, got)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if d := equalJitter(time.Second); d < 500*time.Millisecond || d > time.Second {
+			t.Fatalf("Expected jitter within [500ms, 1s], but got %v", d)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"unavailable", connect.NewError(connect.CodeUnavailable, nil), true},
+		{"rate limited", connect.NewError(connect.CodeResourceExhausted, nil), true},
+		{"unauthenticated", connect.NewError(connect.CodeUnauthenticated, nil), false},
+		{"not found", connect.NewError(connect.CodeNotFound, nil), false},
+		{"aws throttling", &smithy.GenericAPIError{Code: "ThrottlingException"}, true},
+		{"aws server fault", &smithy.GenericAPIError{Code: "Oops", Fault: smithy.FaultServer}, true},
+		{"aws access denied", &smithy.GenericAPIError{Code: "AccessDeniedException", Fault: smithy.FaultClient}, false},
+		{"network", errors.WithStack(&net.OpError{Op: "dial", Err: errors.New("refused")}), true},
+		{"unexpected eof", errors.Wrap(io.ErrUnexpectedEOF, "reading"), true},
+		{"cancelled", context.Canceled, false},
+		{"other", errors.New("boom"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := IsTransient(test.err); result != test.expected {
+				t.Errorf("Expected %v, but got %v", test.expected, result)
+			}
+		})
+	}
+}
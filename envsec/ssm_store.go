@@ -19,6 +19,15 @@ type SSMStore struct {
 // SSMStore implements interface Store (compile-time check)
 var _ Store = (*SSMStore)(nil)
 
+// SSMStore implements interface EnvNameLister (compile-time check)
+var _ EnvNameLister = (*SSMStore)(nil)
+
+// SSMStore implements interface NameLister (compile-time check)
+var _ NameLister = (*SSMStore)(nil)
+
+// SSMStore implements interface Versioned (compile-time check)
+var _ Versioned = (*SSMStore)(nil)
+
 func newSSMStore(ctx context.Context, config *SSMConfig) (*SSMStore, error) {
 	paramStore, err := newParameterStore(ctx, config)
 	if err != nil {
@@ -31,10 +40,30 @@ func newSSMStore(ctx context.Context, config *SSMConfig) (*SSMStore, error) {
 }
 
 func (s *SSMStore) List(ctx context.Context, envID EnvID) ([]EnvVar, error) {
+	var vars []EnvVar
+	var err error
 	if s.store.config.hasDefaultPaths() {
-		return s.store.listByPath(ctx, envID)
+		vars, err = s.store.listByPath(ctx, envID)
+	} else {
+		vars, err = s.store.listByTags(ctx, envID)
+	}
+	return vars, classifyError(err)
+}
+
+func (s *SSMStore) ListNames(ctx context.Context, envID EnvID) ([]string, error) {
+	names, err := s.store.listNames(ctx, envID)
+	return names, classifyError(err)
+}
+
+// ListEnvNames only works with the default paths, where the environment name
+// is part of each parameter's path. Parameters only tagged with it would have
+// to be fetched one by one.
+func (s *SSMStore) ListEnvNames(ctx context.Context, envID EnvID) ([]string, error) {
+	if !s.store.config.hasDefaultPaths() {
+		return nil, errors.WithStack(ErrEnvNamesUnsupported)
 	}
-	return s.store.listByTags(ctx, envID)
+	envNames, err := s.store.listEnvNamesByPath(ctx, envID)
+	return envNames, classifyError(err)
 }
 
 func (s *SSMStore) Get(ctx context.Context, envID EnvID, name string) (string, error) {
@@ -43,13 +72,21 @@ func (s *SSMStore) Get(ctx context.Context, envID EnvID, name string) (string, e
 		return "", errors.WithStack(err)
 	}
 	if len(vars) == 0 {
-		return "", nil
+		return "", KeyNotFound(name)
 	}
 	return vars[0].Value, nil
 }
 
+// History returns the versions Parameter Store keeps of the parameter, which
+// are at most the last 100.
+func (s *SSMStore) History(ctx context.Context, envID EnvID, name string) ([]Version, error) {
+	versions, err := s.store.history(ctx, envID, name)
+	return versions, classifyError(err)
+}
+
 func (s *SSMStore) GetAll(ctx context.Context, envID EnvID, names []string) ([]EnvVar, error) {
-	return s.store.getAll(ctx, envID, names)
+	vars, err := s.store.getAll(ctx, envID, names)
+	return vars, classifyError(err)
 }
 
 func (s *SSMStore) Set(
@@ -66,7 +103,7 @@ func (s *SSMStore) Set(
 		tags: tags,
 		id:   path,
 	}
-	return s.store.newParameter(ctx, parameter, value)
+	return classifyError(s.store.newParameter(ctx, parameter, value))
 }
 
 func (s *SSMStore) SetAll(ctx context.Context, envID EnvID, values map[string]string) error {
@@ -89,7 +126,7 @@ func (s *SSMStore) Delete(ctx context.Context, envID EnvID, name string) error {
 }
 
 func (s *SSMStore) DeleteAll(ctx context.Context, envID EnvID, names []string) error {
-	return s.store.deleteAll(ctx, envID, names)
+	return classifyError(s.store.deleteAll(ctx, envID, names))
 }
 
 func buildTags(envID EnvID, varName string) []types.Tag {